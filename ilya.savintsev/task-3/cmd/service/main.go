@@ -2,48 +2,235 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"time"
 
 	filesaver "github.com/faxryzen/task-3/internal/file_saver"
+	ratestore "github.com/faxryzen/task-3/internal/rate_store"
 	valsys "github.com/faxryzen/task-3/internal/valute_system"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	defaultPlaces = 4
+	defaultStore  = "rates"
+)
+
 type DirHandle struct {
-	InputFile  string `yaml:"input-file"`
-	OutputFile string `yaml:"output-file"`
+	InputFile    string `yaml:"input-file"`
+	OutputFile   string `yaml:"output-file"`
+	OutputFormat string `yaml:"output-format"`
+	SortBy       string `yaml:"sort-by"`
 }
 
 func main() {
+	commands := map[string]func(args []string){
+		"convert": convert,
+		"ingest":  ingest,
+		"history": history,
+		"stats":   stats,
+		"change":  change,
+	}
+
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+
+			return
+		}
+	}
+
 	var fileDir string
 
 	flag.StringVar(&fileDir, "config", "yaml", "Specifies the path to the config")
 	flag.Parse()
 
-	content, err := os.ReadFile(fileDir)
+	config := readConfig(fileDir)
+
+	curs, err := valsys.ParseXML(config.InputFile)
 	if err != nil {
-		panic("no such file or directory")
+		panic(err)
 	}
 
-	var config DirHandle
+	data, err := valsys.Encode(curs, config.OutputFormat, valsys.SortKey(config.SortBy))
+	if err != nil {
+		panic(err)
+	}
 
-	err = yaml.Unmarshal(content, &config)
+	err = filesaver.SaveToFile(data, config.OutputFile)
 	if err != nil {
-		panic("did not find expected key")
+		panic(err)
+	}
+}
+
+func convert(args []string) {
+	runConvert(os.Stdout, args)
+}
+
+func runConvert(out io.Writer, args []string) {
+	var (
+		fileDir  string
+		amount   string
+		from     string
+		to       string
+		rounding valsys.Rounding
+		mode     string
+	)
+
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	flags.StringVar(&fileDir, "config", "yaml", "Specifies the path to the config")
+	flags.StringVar(&amount, "amount", "1", "Amount to convert")
+	flags.StringVar(&from, "from", valsys.BaseCurrency, "Source currency code")
+	flags.StringVar(&to, "to", valsys.BaseCurrency, "Target currency code")
+	flags.IntVar(&rounding.Places, "places", defaultPlaces, "Decimal places of the result")
+	flags.StringVar(&mode, "rounding", string(valsys.RoundHalfUp), "Rounding mode: half-up, half-even or down")
+
+	if err := flags.Parse(args); err != nil {
+		panic(err)
 	}
 
+	rounding.Mode = valsys.RoundingMode(mode)
+
+	config := readConfig(fileDir)
+
 	curs, err := valsys.ParseXML(config.InputFile)
 	if err != nil {
 		panic(err)
 	}
 
-	jsonData, err := valsys.CreateJSON(curs)
+	value, err := valsys.ParseDecimal(amount)
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := curs.Convert(value, from, to, rounding)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintln(out, result.StringFixed(rounding.Places))
+}
+
+func ingest(args []string) {
+	var storeDir string
+
+	flags := flag.NewFlagSet("ingest", flag.ExitOnError)
+	flags.StringVar(&storeDir, "store", defaultStore, "Directory of the rate store")
+
+	if err := flags.Parse(args); err != nil {
+		panic(err)
+	}
+
+	store, err := ratestore.Open(storeDir)
 	if err != nil {
 		panic(err)
 	}
 
-	err = filesaver.SaveToFile(jsonData, config.OutputFile)
+	for _, file := range flags.Args() {
+		curs, err := valsys.ParseXML(file)
+		if err != nil {
+			panic(fmt.Errorf("%s: %w", file, err))
+		}
+
+		day, err := store.Ingest(curs)
+		if err != nil {
+			panic(fmt.Errorf("%s: %w", file, err))
+		}
+
+		fmt.Println(day.Format(ratestore.DateLayout), file)
+	}
+}
+
+func history(args []string) {
+	store, code, from, to := parseQuery("history", args)
+
+	points, err := store.History(code, from, to)
 	if err != nil {
 		panic(err)
 	}
+
+	printJSON(points)
+}
+
+func stats(args []string) {
+	store, code, from, to := parseQuery("stats", args)
+
+	summary, err := store.Stats(code, from, to)
+	if err != nil {
+		panic(err)
+	}
+
+	printJSON(summary)
+}
+
+func change(args []string) {
+	store, code, from, to := parseQuery("change", args)
+
+	changes, err := store.Changes(code, from, to)
+	if err != nil {
+		panic(err)
+	}
+
+	printJSON(changes)
+}
+
+func parseQuery(name string, args []string) (*ratestore.Store, string, time.Time, time.Time) {
+	var storeDir, code, from, to string
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.StringVar(&storeDir, "store", defaultStore, "Directory of the rate store")
+	flags.StringVar(&code, "code", "USD", "Currency code")
+	flags.StringVar(&from, "from", "", "First date of the range, YYYY-MM-DD")
+	flags.StringVar(&to, "to", "", "Last date of the range, YYYY-MM-DD")
+
+	if err := flags.Parse(args); err != nil {
+		panic(err)
+	}
+
+	store, err := ratestore.Open(storeDir)
+	if err != nil {
+		panic(err)
+	}
+
+	return store, code, parseDate(from), parseDate(to)
+}
+
+func parseDate(text string) time.Time {
+	if text == "" {
+		return time.Time{}
+	}
+
+	day, err := time.Parse(ratestore.DateLayout, text)
+	if err != nil {
+		panic(fmt.Errorf("invalid date %q: %w", text, err))
+	}
+
+	return day
+}
+
+func printJSON(value any) {
+	data, err := ratestore.EncodeJSON(value)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(string(data))
+}
+
+func readConfig(fileDir string) DirHandle {
+	content, err := os.ReadFile(fileDir)
+	if err != nil {
+		panic("no such file or directory")
+	}
+
+	var config DirHandle
+
+	err = yaml.Unmarshal(content, &config)
+	if err != nil {
+		panic("did not find expected key")
+	}
+
+	return config
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const convertRates = `<?xml version="1.0" encoding="UTF-8"?>
+<ValCurs Date="02.03.2024" name="Foreign Currency Market">
+	<Valute ID="R01235">
+		<NumCode>840</NumCode>
+		<CharCode>USD</CharCode>
+		<Nominal>1</Nominal>
+		<Name>Доллар США</Name>
+		<Value>91,3336</Value>
+		<VunitRate>91,3336</VunitRate>
+	</Valute>
+	<Valute ID="R01820">
+		<NumCode>392</NumCode>
+		<CharCode>JPY</CharCode>
+		<Nominal>100</Nominal>
+		<Name>Японских иен</Name>
+		<Value>60,8577</Value>
+	</Valute>
+</ValCurs>`
+
+func TestConvertCommand(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "rates.xml")
+	config := filepath.Join(dir, "config.yaml")
+
+	if err := os.WriteFile(input, []byte(convertRates), 0o600); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+
+	if err := os.WriteFile(config, []byte("input-file: "+input+"\n"), 0o600); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+
+	cases := map[string][]string{
+		"6.66\n":      {"-amount", "1000", "-from", "JPY", "-to", "usd", "-places", "2"},
+		"150.0773\n":  {"-amount", "1", "-from", "USD", "-to", "JPY"},
+		"913.33600\n": {"-amount", "10", "-from", "USD", "-places", "5", "-rounding", "down"},
+	}
+
+	for want, args := range cases {
+		var out bytes.Buffer
+
+		runConvert(&out, append([]string{"-config", config}, args...))
+
+		if out.String() != want {
+			t.Fatalf("convert %v printed %q, want %q", args, out.String(), want)
+		}
+	}
+}
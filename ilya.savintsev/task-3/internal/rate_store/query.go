@@ -0,0 +1,171 @@
+package ratestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	valsys "github.com/faxryzen/task-3/internal/valute_system"
+)
+
+const percentPlaces = 4
+
+var (
+	errNoData   = errors.New("no rates for currency in range")
+	errMarsJSON = errors.New("cant marshall json")
+)
+
+type Point struct {
+	Date     string         `json:"date"`
+	CharCode string         `json:"char_code"`
+	Nominal  int            `json:"nominal"`
+	Value    valsys.Decimal `json:"value"`
+	UnitRate valsys.Decimal `json:"unit_rate"`
+}
+
+type Extreme struct {
+	Date     string         `json:"date"`
+	UnitRate valsys.Decimal `json:"unit_rate"`
+}
+
+type Stats struct {
+	CharCode string         `json:"char_code"`
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	Days     int            `json:"days"`
+	Min      Extreme        `json:"min"`
+	Max      Extreme        `json:"max"`
+	Average  valsys.Decimal `json:"average"`
+}
+
+type Change struct {
+	Date     string         `json:"date"`
+	PrevDate string         `json:"prev_date"`
+	UnitRate valsys.Decimal `json:"unit_rate"`
+	Delta    valsys.Decimal `json:"delta"`
+	Percent  valsys.Decimal `json:"percent"`
+}
+
+func (s *Store) History(code string, from, to time.Time) ([]Point, error) {
+	code = strings.ToUpper(code)
+
+	days, err := s.Days(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]Point, 0, len(days))
+
+	for _, day := range days {
+		valutes, err := s.Valutes(day)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, value := range valutes {
+			if value.CharCode != code {
+				continue
+			}
+
+			points = append(points, Point{
+				Date:     day.Format(DateLayout),
+				CharCode: value.CharCode,
+				Nominal:  value.Nominal,
+				Value:    value.Value,
+				UnitRate: value.UnitRate(),
+			})
+
+			break
+		}
+	}
+
+	if len(points) == 0 {
+		return nil, fmt.Errorf("%w: %s", errNoData, code)
+	}
+
+	return points, nil
+}
+
+func (s *Store) Stats(code string, from, to time.Time) (Stats, error) {
+	points, err := s.History(code, from, to)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	first, last := points[0], points[len(points)-1]
+	low := Extreme{Date: first.Date, UnitRate: first.UnitRate}
+	high := low
+	sum := first.UnitRate
+
+	for _, point := range points[1:] {
+		if point.UnitRate.Cmp(low.UnitRate) < 0 {
+			low = Extreme{Date: point.Date, UnitRate: point.UnitRate}
+		}
+
+		if point.UnitRate.Cmp(high.UnitRate) > 0 {
+			high = Extreme{Date: point.Date, UnitRate: point.UnitRate}
+		}
+
+		sum = sum.Add(point.UnitRate)
+	}
+
+	average, err := sum.Div(valsys.DecimalFromInt(int64(len(points))))
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return Stats{
+		CharCode: first.CharCode,
+		From:     first.Date,
+		To:       last.Date,
+		Days:     len(points),
+		Min:      low,
+		Max:      high,
+		Average:  average,
+	}, nil
+}
+
+func (s *Store) Changes(code string, from, to time.Time) ([]Change, error) {
+	points, err := s.History(code, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, 0, len(points)-1)
+
+	for i := 1; i < len(points); i++ {
+		prev, curr := points[i-1], points[i]
+
+		delta := curr.UnitRate.Sub(prev.UnitRate)
+
+		percent, err := percentOf(delta, prev.UnitRate)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, Change{
+			Date:     curr.Date,
+			PrevDate: prev.Date,
+			UnitRate: curr.UnitRate,
+			Delta:    delta,
+			Percent:  percent,
+		})
+	}
+
+	return changes, nil
+}
+
+func EncodeJSON(value any) ([]byte, error) {
+	jsonData, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, errMarsJSON
+	}
+
+	return jsonData, nil
+}
+
+func percentOf(delta, base valsys.Decimal) (valsys.Decimal, error) {
+	return delta.Mul(valsys.DecimalFromInt(100)).DivRound(base, percentPlaces, valsys.RoundHalfEven)
+}
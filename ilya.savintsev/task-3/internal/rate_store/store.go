@@ -0,0 +1,180 @@
+package ratestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	valsys "github.com/faxryzen/task-3/internal/valute_system"
+)
+
+const (
+	CursDateLayout = "02.01.2006"
+	DateLayout     = "2006-01-02"
+
+	indexFile   = "index.json"
+	ownerRW     = 0o600
+	allReadExec = 0o755
+)
+
+var (
+	errStoreDir  = errors.New("unable create store directory")
+	errReadStore = errors.New("unable read store")
+	errWrtStore  = errors.New("unable write store")
+	errCorrupt   = errors.New("corrupted store")
+	errCursDate  = errors.New("invalid curs date")
+)
+
+type Store struct {
+	mu  sync.Mutex
+	dir string
+}
+
+type index struct {
+	Days []string `json:"days"`
+}
+
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, allReadExec); err != nil {
+		return nil, fmt.Errorf("%w: %s", errStoreDir, dir)
+	}
+
+	return &Store{mu: sync.Mutex{}, dir: dir}, nil
+}
+
+func (s *Store) Ingest(curs *valsys.ValCurs) (time.Time, error) {
+	day, err := time.Parse(CursDateLayout, curs.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q", errCursDate, curs.Date)
+	}
+
+	data, err := valsys.Encode(curs, valsys.FullJSONFormat, valsys.SortByValue)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	key := day.Format(DateLayout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write(key+".json", data); err != nil {
+		return time.Time{}, err
+	}
+
+	idx, err := s.readIndex()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	pos := sort.SearchStrings(idx.Days, key)
+	if pos < len(idx.Days) && idx.Days[pos] == key {
+		return day, nil
+	}
+
+	idx.Days = append(idx.Days, "")
+	copy(idx.Days[pos+1:], idx.Days[pos:])
+	idx.Days[pos] = key
+
+	data, err = json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return time.Time{}, errWrtStore
+	}
+
+	return day, s.write(indexFile, data)
+}
+
+func (s *Store) Days(from, to time.Time) ([]time.Time, error) {
+	idx, err := s.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]time.Time, 0, len(idx.Days))
+
+	for _, key := range idx.Days {
+		day, err := time.Parse(DateLayout, key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errCorrupt, indexFile)
+		}
+
+		if inRange(day, from, to) {
+			days = append(days, day)
+		}
+	}
+
+	return days, nil
+}
+
+func (s *Store) Valutes(day time.Time) ([]valsys.Valute, error) {
+	name := day.Format(DateLayout) + ".json"
+
+	content, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errReadStore, name)
+	}
+
+	var valutes []valsys.Valute
+	if err := json.Unmarshal(content, &valutes); err != nil {
+		return nil, fmt.Errorf("%w: %s", errCorrupt, name)
+	}
+
+	return valutes, nil
+}
+
+func (s *Store) readIndex() (index, error) {
+	content, err := os.ReadFile(filepath.Join(s.dir, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return index{Days: nil}, nil
+	}
+
+	if err != nil {
+		return index{Days: nil}, fmt.Errorf("%w: %s", errReadStore, indexFile)
+	}
+
+	var idx index
+	if err := json.Unmarshal(content, &idx); err != nil {
+		return index{Days: nil}, fmt.Errorf("%w: %s", errCorrupt, indexFile)
+	}
+
+	sort.Strings(idx.Days)
+
+	return idx, nil
+}
+
+func (s *Store) write(name string, data []byte) error {
+	temp, err := os.CreateTemp(s.dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: %s", errWrtStore, name)
+	}
+
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(temp.Name(), ownerRW)
+	}
+
+	if err == nil {
+		err = os.Rename(temp.Name(), filepath.Join(s.dir, name))
+	}
+
+	if err != nil {
+		_ = os.Remove(temp.Name())
+
+		return fmt.Errorf("%w: %s", errWrtStore, name)
+	}
+
+	return nil
+}
+
+func inRange(day, from, to time.Time) bool {
+	return (from.IsZero() || !day.Before(from)) && (to.IsZero() || !day.After(to))
+}
@@ -0,0 +1,197 @@
+package ratestore_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	ratestore "github.com/faxryzen/task-3/internal/rate_store"
+	valsys "github.com/faxryzen/task-3/internal/valute_system"
+)
+
+func ingest(t *testing.T, store *ratestore.Store, date, usd string) {
+	t.Helper()
+
+	rate, err := valsys.ParseDecimal(usd)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	curs := &valsys.ValCurs{
+		Date: date,
+		Name: "Foreign Currency Market",
+		Valutes: []valsys.Valute{{
+			ID:        "R01235",
+			NumCode:   840,
+			CharCode:  "USD",
+			Nominal:   1,
+			Name:      "Доллар США",
+			Value:     rate,
+			VunitRate: &rate,
+		}},
+	}
+
+	if _, err := store.Ingest(curs); err != nil {
+		t.Fatalf("unexpected ingest error: %v", err)
+	}
+}
+
+func TestStoreQueries(t *testing.T) {
+	t.Parallel()
+
+	store, err := ratestore.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+
+	ingest(t, store, "12.01.2024", "90,1012")
+	ingest(t, store, "10.01.2024", "89,6883")
+	ingest(t, store, "11.01.2024", "89,4565")
+	ingest(t, store, "10.01.2024", "89,6883")
+
+	points, err := store.History("usd", time.Time{}, time.Time{})
+	if err != nil || len(points) != 3 || points[0].Date != "2024-01-10" || points[2].Date != "2024-01-12" {
+		t.Fatalf("unexpected history: %+v, %v", points, err)
+	}
+
+	from := time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)
+
+	points, err = store.History("USD", from, time.Time{})
+	if err != nil || len(points) != 2 {
+		t.Fatalf("unexpected ranged history: %+v, %v", points, err)
+	}
+
+	stats, err := store.Stats("USD", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected stats error: %v", err)
+	}
+
+	if stats.Min.Date != "2024-01-11" || stats.Max.Date != "2024-01-12" || stats.Average.String() != "89.74866667" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	changes, err := store.Changes("USD", time.Time{}, time.Time{})
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("unexpected changes: %+v, %v", changes, err)
+	}
+
+	if changes[0].Delta.String() != "-0.2318" || changes[1].Percent.String() != "0.7207" {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+
+	if _, err := store.History("EUR", time.Time{}, time.Time{}); err == nil {
+		t.Fatalf("expected error for missing currency")
+	}
+}
+
+func TestStoreKeepsFullRecords(t *testing.T) {
+	t.Parallel()
+
+	store, err := ratestore.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+
+	value, err := valsys.ParseDecimal("60,8577")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	curs := &valsys.ValCurs{
+		Date: "02.03.2024",
+		Name: "Foreign Currency Market",
+		Valutes: []valsys.Valute{{
+			ID:        "R01820",
+			NumCode:   392,
+			CharCode:  "JPY",
+			Nominal:   100,
+			Name:      "Японских иен",
+			Value:     value,
+			VunitRate: nil,
+		}},
+	}
+
+	if _, err := store.Ingest(curs); err != nil {
+		t.Fatalf("unexpected ingest error: %v", err)
+	}
+
+	points, err := store.History("JPY", time.Time{}, time.Time{})
+	if err != nil || len(points) != 1 || points[0].Nominal != 100 || points[0].UnitRate.String() != "0.608577" {
+		t.Fatalf("unexpected history: %+v, %v", points, err)
+	}
+}
+
+func TestChangesRoundPercentOnce(t *testing.T) {
+	t.Parallel()
+
+	store, err := ratestore.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+
+	ingest(t, store, "10.01.2024", "80,0007")
+	ingest(t, store, "11.01.2024", "80,0013")
+
+	changes, err := store.Changes("USD", time.Time{}, time.Time{})
+	if err != nil || len(changes) != 1 || changes[0].Percent.String() != "0.0007" {
+		t.Fatalf("unexpected changes: %+v, %v", changes, err)
+	}
+}
+
+func TestConcurrentIngestKeepsEveryDay(t *testing.T) {
+	t.Parallel()
+
+	store, err := ratestore.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+
+	const days = 20
+
+	rate, err := valsys.ParseDecimal("90")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	errs := make(chan error, days)
+
+	var wg sync.WaitGroup
+
+	for day := 1; day <= days; day++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := store.Ingest(&valsys.ValCurs{
+				Date: fmt.Sprintf("%02d.01.2024", day),
+				Name: "Foreign Currency Market",
+				Valutes: []valsys.Valute{{
+					ID:        "R01235",
+					NumCode:   840,
+					CharCode:  "USD",
+					Nominal:   1,
+					Name:      "Доллар США",
+					Value:     rate,
+					VunitRate: nil,
+				}},
+			})
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected ingest error: %v", err)
+		}
+	}
+
+	stored, err := store.Days(time.Time{}, time.Time{})
+	if err != nil || len(stored) != days {
+		t.Fatalf("expected %d days, got %d: %v", days, len(stored), err)
+	}
+}
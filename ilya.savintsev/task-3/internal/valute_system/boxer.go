@@ -1,34 +1,56 @@
 package valsys
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"sort"
 )
 
-var errMarsJSON = errors.New("cant marshall json")
+type SortKey string
 
-func CreateJSON(curs *ValCurs) ([]byte, error) {
-	cursTemp := make([]Valute, 0, len(curs.Valutes))
+const (
+	SortByValue    SortKey = "value"
+	SortByUnitRate SortKey = "unit-rate"
+	SortByCode     SortKey = "code"
+	SortByName     SortKey = "name"
+)
 
-	for _, value := range curs.Valutes {
-		valTemp := Valute{
-			NumCode:  value.NumCode,
-			CharCode: value.CharCode,
-			Value:    value.Value,
-		}
+var (
+	errMarsJSON    = errors.New("cant marshall json")
+	errUnknownSort = errors.New("unknown sort key")
+)
 
-		cursTemp = append(cursTemp, valTemp)
+func CreateJSON(curs *ValCurs) ([]byte, error) {
+	valutes, err := SortValutes(curs, SortByValue)
+	if err != nil {
+		return nil, err
 	}
 
-	sort.Slice(cursTemp, func(i, j int) bool {
-		return cursTemp[i].Value > cursTemp[j].Value
-	})
+	return encodeJSON(valutes)
+}
 
-	jsonData, err := json.MarshalIndent(cursTemp, "", "  ")
-	if err != nil {
-		return nil, errMarsJSON
+func SortValutes(curs *ValCurs, key SortKey) ([]Valute, error) {
+	cursTemp := make([]Valute, len(curs.Valutes))
+	copy(cursTemp, curs.Valutes)
+
+	var less func(left, right Valute) bool
+
+	switch key {
+	case SortByValue, "":
+		less = func(left, right Valute) bool { return left.Value.Cmp(right.Value) > 0 }
+	case SortByUnitRate:
+		less = func(left, right Valute) bool { return left.UnitRate().Cmp(right.UnitRate()) > 0 }
+	case SortByCode:
+		less = func(left, right Valute) bool { return left.CharCode < right.CharCode }
+	case SortByName:
+		less = func(left, right Valute) bool { return left.Name < right.Name }
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownSort, key)
 	}
 
-	return jsonData, nil
+	sort.SliceStable(cursTemp, func(i, j int) bool {
+		return less(cursTemp[i], cursTemp[j])
+	})
+
+	return cursTemp, nil
 }
@@ -0,0 +1,90 @@
+package valsys_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	valsys "github.com/faxryzen/task-3/internal/valute_system"
+)
+
+func charCodes(valutes []valsys.Valute) []string {
+	codes := make([]string, 0, len(valutes))
+
+	for _, value := range valutes {
+		codes = append(codes, value.CharCode)
+	}
+
+	return codes
+}
+
+func TestCreateJSONKeepsOriginalFields(t *testing.T) {
+	t.Parallel()
+
+	data, err := valsys.CreateJSON(sampleCurs(t))
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+
+	var decoded []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	if len(decoded) != 2 || len(decoded[0]) != 3 || string(decoded[0]["char_code"]) != `"USD"` ||
+		string(decoded[0]["num_code"]) != "840" || string(decoded[0]["value"]) != "91.3336" {
+		t.Fatalf("unexpected default JSON:\n%s", data)
+	}
+
+	full, err := valsys.Encode(sampleCurs(t), valsys.FullJSONFormat, valsys.SortByValue)
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+
+	decoded = nil
+	if err := json.Unmarshal(full, &decoded); err != nil || len(decoded[0]) != 7 {
+		t.Fatalf("unexpected full JSON: %v\n%s", err, full)
+	}
+}
+
+func TestSortValutes(t *testing.T) {
+	t.Parallel()
+
+	curs := sampleCurs(t)
+	curs.Valutes = append(curs.Valutes, valsys.Valute{
+		ID:        "R01035",
+		NumCode:   826,
+		CharCode:  "GBP",
+		Nominal:   1,
+		Name:      "Фунт стерлингов",
+		Value:     mustDecimal(t, "115,5"),
+		VunitRate: mustRate(t, "115,5"),
+	})
+
+	cases := map[valsys.SortKey]string{
+		valsys.SortByValue:    "GBP,USD,JPY",
+		"":                    "GBP,USD,JPY",
+		valsys.SortByUnitRate: "GBP,USD,JPY",
+		valsys.SortByCode:     "GBP,JPY,USD",
+		valsys.SortByName:     "USD,GBP,JPY",
+	}
+
+	for key, want := range cases {
+		sorted, err := valsys.SortValutes(curs, key)
+		if err != nil {
+			t.Fatalf("%q: unexpected sort error: %v", key, err)
+		}
+
+		if got := strings.Join(charCodes(sorted), ","); got != want {
+			t.Fatalf("%q: sorted %s, want %s", key, got, want)
+		}
+	}
+
+	if curs.Valutes[0].CharCode != "USD" {
+		t.Fatalf("sorting changed the source order: %v", charCodes(curs.Valutes))
+	}
+
+	if _, err := valsys.SortValutes(curs, "date"); err == nil {
+		t.Fatalf("expected unknown sort key error")
+	}
+}
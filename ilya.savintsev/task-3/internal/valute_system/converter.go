@@ -0,0 +1,102 @@
+package valsys
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const BaseCurrency = "RUB"
+
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half-up"
+	RoundHalfEven RoundingMode = "half-even"
+	RoundDown     RoundingMode = "down"
+)
+
+var (
+	errUnknownCurr  = errors.New("unknown currency")
+	errInvalidRate  = errors.New("invalid rate")
+	errUnknownRound = errors.New("unknown rounding mode")
+)
+
+type Rounding struct {
+	Places int
+	Mode   RoundingMode
+}
+
+func (c *ValCurs) Rate(code string) (Decimal, error) {
+	rate, nominal, err := c.rate(code)
+	if err != nil {
+		return Decimal{units: nil}, err
+	}
+
+	return quotient([]Decimal{rate}, []Decimal{nominal}, Rounding{Places: DecimalPlaces, Mode: RoundHalfEven})
+}
+
+func (c *ValCurs) Convert(amount Decimal, from, to string, rounding Rounding) (Decimal, error) {
+	fromRate, fromNominal, err := c.rate(from)
+	if err != nil {
+		return Decimal{units: nil}, err
+	}
+
+	toRate, toNominal, err := c.rate(to)
+	if err != nil {
+		return Decimal{units: nil}, err
+	}
+
+	return quotient([]Decimal{amount, fromRate, toNominal}, []Decimal{fromNominal, toRate}, rounding)
+}
+
+func (c *ValCurs) rate(code string) (Decimal, Decimal, error) {
+	code = strings.ToUpper(code)
+
+	one := DecimalFromInt(1)
+
+	if code == BaseCurrency {
+		return one, one, nil
+	}
+
+	for _, value := range c.Valutes {
+		if value.CharCode != code {
+			continue
+		}
+
+		rate, nominal := value.Value, one
+
+		if value.VunitRate != nil {
+			rate = *value.VunitRate
+		} else if value.Nominal > 1 {
+			nominal = DecimalFromInt(int64(value.Nominal))
+		}
+
+		if rate.Sign() <= 0 {
+			return Decimal{units: nil}, Decimal{units: nil}, fmt.Errorf("%w: %s", errInvalidRate, code)
+		}
+
+		return rate, nominal, nil
+	}
+
+	return Decimal{units: nil}, Decimal{units: nil}, fmt.Errorf("%w: %s", errUnknownCurr, code)
+}
+
+func (r Rounding) Apply(value Decimal) (Decimal, error) {
+	return value.Round(r.Places, r.Mode)
+}
+
+func (r Rounding) mode() (RoundingMode, error) {
+	if r.Places < 0 || r.Places > DecimalPlaces {
+		return "", fmt.Errorf("%w: %d", errPlaces, r.Places)
+	}
+
+	switch r.Mode {
+	case RoundHalfUp, RoundHalfEven, RoundDown:
+		return r.Mode, nil
+	case "":
+		return RoundHalfUp, nil
+	default:
+		return "", fmt.Errorf("%w: %q", errUnknownRound, r.Mode)
+	}
+}
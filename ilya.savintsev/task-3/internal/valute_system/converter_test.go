@@ -0,0 +1,84 @@
+package valsys_test
+
+import (
+	"testing"
+
+	valsys "github.com/faxryzen/task-3/internal/valute_system"
+)
+
+func TestRateUsesNominal(t *testing.T) {
+	t.Parallel()
+
+	curs := sampleCurs(t)
+	curs.Valutes[1].VunitRate = nil
+
+	cases := map[string]string{
+		"JPY": "0.608577",
+		"usd": "91.3336",
+		"RUB": "1",
+	}
+
+	for code, want := range cases {
+		rate, err := curs.Rate(code)
+		if err != nil || rate.String() != want {
+			t.Fatalf("Rate(%s) = %v, %v, want %s", code, rate, err, want)
+		}
+	}
+
+	if _, err := curs.Rate("EUR"); err == nil {
+		t.Fatalf("expected error for unknown currency")
+	}
+}
+
+func TestConvertCrossRates(t *testing.T) {
+	t.Parallel()
+
+	curs := sampleCurs(t)
+	rounding := valsys.Rounding{Places: 4, Mode: valsys.RoundHalfUp}
+
+	cases := []struct {
+		amount, from, to, want string
+	}{
+		{amount: "1000", from: "JPY", to: "USD", want: "6.6632"},
+		{amount: "1", from: "USD", to: "JPY", want: "150.0773"},
+		{amount: "10", from: "USD", to: "RUB", want: "913.3360"},
+		{amount: "100", from: "RUB", to: "JPY", want: "164.3177"},
+		{amount: "5", from: "JPY", to: "JPY", want: "5.0000"},
+	}
+
+	for _, current := range cases {
+		result, err := curs.Convert(mustDecimal(t, current.amount), current.from, current.to, rounding)
+		if err != nil {
+			t.Fatalf("%s %s -> %s: unexpected error: %v", current.amount, current.from, current.to, err)
+		}
+
+		if got := result.StringFixed(rounding.Places); got != current.want {
+			t.Fatalf("%s %s -> %s = %s, want %s", current.amount, current.from, current.to, got, current.want)
+		}
+	}
+}
+
+func TestConvertRoundsOnce(t *testing.T) {
+	t.Parallel()
+
+	curs := &valsys.ValCurs{
+		Date: "02.03.2024",
+		Name: "Foreign Currency Market",
+		Valutes: []valsys.Valute{{
+			ID:        "R00000",
+			NumCode:   963,
+			CharCode:  "XTS",
+			Nominal:   1,
+			Name:      "Test",
+			Value:     mustDecimal(t, "3"),
+			VunitRate: mustRate(t, "3"),
+		}},
+	}
+
+	rounding := valsys.Rounding{Places: 0, Mode: valsys.RoundHalfUp}
+
+	result, err := curs.Convert(mustDecimal(t, "1.49999999"), "RUB", "XTS", rounding)
+	if err != nil || result.String() != "0" {
+		t.Fatalf("expected 0.49999999(6) to round down once, got %v, %v", result, err)
+	}
+}
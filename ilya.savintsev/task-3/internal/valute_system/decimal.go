@@ -0,0 +1,236 @@
+package valsys
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const DecimalPlaces = 8
+
+var (
+	errInvalidDec = errors.New("invalid decimal")
+	errDivZero    = errors.New("division by zero")
+	errPlaces     = errors.New("invalid decimal places")
+)
+
+var decimalScale = pow10(DecimalPlaces)
+
+type Decimal struct {
+	units *big.Int
+}
+
+func DecimalFromInt(value int64) Decimal {
+	return fromBig(new(big.Int).Mul(big.NewInt(value), decimalScale))
+}
+
+func ParseDecimal(text string) (Decimal, error) {
+	trimmed := strings.TrimSpace(text)
+
+	trimmed, negative := strings.CutPrefix(trimmed, "-")
+	if !negative {
+		trimmed = strings.TrimPrefix(trimmed, "+")
+	}
+
+	intPart, fracPart, found := strings.Cut(strings.Replace(trimmed, ",", ".", 1), ".")
+	if intPart == "" || (found && fracPart == "") || !onlyDigits(intPart) || !onlyDigits(fracPart) {
+		return Decimal{units: nil}, fmt.Errorf("%w: %q", errInvalidDec, text)
+	}
+
+	digits, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok {
+		return Decimal{units: nil}, fmt.Errorf("%w: %q", errInvalidDec, text)
+	}
+
+	if negative {
+		digits.Neg(digits)
+	}
+
+	if len(fracPart) <= DecimalPlaces {
+		return fromBig(digits.Mul(digits, pow10(DecimalPlaces-len(fracPart)))), nil
+	}
+
+	return fromBig(roundQuo(digits, pow10(len(fracPart)-DecimalPlaces), RoundHalfUp)), nil
+}
+
+func (d Decimal) Cmp(other Decimal) int {
+	return d.value().Cmp(other.value())
+}
+
+func (d Decimal) Sign() int {
+	return d.value().Sign()
+}
+
+func (d Decimal) Add(other Decimal) Decimal {
+	return fromBig(new(big.Int).Add(d.value(), other.value()))
+}
+
+func (d Decimal) Sub(other Decimal) Decimal {
+	return fromBig(new(big.Int).Sub(d.value(), other.value()))
+}
+
+func (d Decimal) Mul(other Decimal) Decimal {
+	product := new(big.Int).Mul(d.value(), other.value())
+
+	return fromBig(roundQuo(product, decimalScale, RoundHalfEven))
+}
+
+func (d Decimal) Div(other Decimal) (Decimal, error) {
+	if other.Sign() == 0 {
+		return Decimal{units: nil}, errDivZero
+	}
+
+	scaled := new(big.Int).Mul(d.value(), decimalScale)
+
+	return fromBig(roundQuo(scaled, other.value(), RoundHalfEven)), nil
+}
+
+func (d Decimal) DivRound(other Decimal, places int, mode RoundingMode) (Decimal, error) {
+	return quotient([]Decimal{d}, []Decimal{other}, Rounding{Places: places, Mode: mode})
+}
+
+func (d Decimal) Round(places int, mode RoundingMode) (Decimal, error) {
+	return quotient([]Decimal{d}, nil, Rounding{Places: places, Mode: mode})
+}
+
+func (d Decimal) String() string {
+	return strings.TrimSuffix(strings.TrimRight(d.StringFixed(DecimalPlaces), "0"), ".")
+}
+
+func (d Decimal) StringFixed(places int) string {
+	places = min(max(places, 0), DecimalPlaces)
+
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+
+	digits := new(big.Int).Abs(d.value()).String()
+	if len(digits) <= DecimalPlaces {
+		digits = strings.Repeat("0", DecimalPlaces-len(digits)+1) + digits
+	}
+
+	point := len(digits) - DecimalPlaces
+	if places == 0 {
+		return sign + digits[:point]
+	}
+
+	return sign + digits[:point] + "." + digits[point:point+places]
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	return d.UnmarshalText([]byte(strings.Trim(string(data), `"`)))
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(data []byte) error {
+	parsed, err := ParseDecimal(string(data))
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+
+	return nil
+}
+
+// MarshalYAML writes a quoted string on purpose: yaml.v2 can only emit an unquoted
+// number through float64, which would drop digits beyond its precision.
+func (d Decimal) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+func (d *Decimal) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+
+	return d.UnmarshalText([]byte(text))
+}
+
+func quotient(factors, divisors []Decimal, rounding Rounding) (Decimal, error) {
+	mode, err := rounding.mode()
+	if err != nil {
+		return Decimal{units: nil}, err
+	}
+
+	num, den := big.NewInt(1), big.NewInt(1)
+
+	for _, factor := range factors {
+		num.Mul(num, factor.value())
+	}
+
+	for _, divisor := range divisors {
+		if divisor.Sign() == 0 {
+			return Decimal{units: nil}, errDivZero
+		}
+
+		den.Mul(den, divisor.value())
+	}
+
+	if exp := rounding.Places + DecimalPlaces*(len(divisors)-len(factors)); exp >= 0 {
+		num.Mul(num, pow10(exp))
+	} else {
+		den.Mul(den, pow10(-exp))
+	}
+
+	rounded := roundQuo(num, den, mode)
+
+	return fromBig(rounded.Mul(rounded, pow10(DecimalPlaces-rounding.Places))), nil
+}
+
+func fromBig(value *big.Int) Decimal {
+	return Decimal{units: value}
+}
+
+func (d Decimal) value() *big.Int {
+	if d.units == nil {
+		return new(big.Int)
+	}
+
+	return d.units
+}
+
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 || mode == RoundDown {
+		return quo
+	}
+
+	half := new(big.Int).Abs(rem)
+	half.Mul(half, big.NewInt(2))
+
+	cmp := half.Cmp(new(big.Int).Abs(den))
+	if cmp > 0 || (cmp == 0 && (mode == RoundHalfUp || quo.Bit(0) == 1)) {
+		quo.Add(quo, big.NewInt(int64(num.Sign()*den.Sign())))
+	}
+
+	return quo
+}
+
+func pow10(exp int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)
+}
+
+func onlyDigits(text string) bool {
+	for _, symbol := range text {
+		if symbol < '0' || symbol > '9' {
+			return false
+		}
+	}
+
+	return true
+}
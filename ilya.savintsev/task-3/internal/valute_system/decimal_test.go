@@ -0,0 +1,155 @@
+package valsys_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	valsys "github.com/faxryzen/task-3/internal/valute_system"
+)
+
+func mustDecimal(t *testing.T, text string) valsys.Decimal {
+	t.Helper()
+
+	value, err := valsys.ParseDecimal(text)
+	if err != nil {
+		t.Fatalf("unexpected parse error for %q: %v", text, err)
+	}
+
+	return value
+}
+
+func mustRate(t *testing.T, text string) *valsys.Decimal {
+	t.Helper()
+
+	value := mustDecimal(t, text)
+
+	return &value
+}
+
+func TestParseDecimal(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"1234,5678":    "1234.5678",
+		" 91.3336 ":    "91.3336",
+		"-0,5":         "-0.5",
+		"+10":          "10",
+		"0,123456789":  "0.12345679",
+		"0,000000005":  "0.00000001",
+		"126,44800000": "126.448",
+	}
+
+	for input, want := range cases {
+		if got := mustDecimal(t, input).String(); got != want {
+			t.Fatalf("ParseDecimal(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "1,2,3", "1.", "abc", "--1", "1e5"} {
+		if _, err := valsys.ParseDecimal(input); err == nil {
+			t.Fatalf("ParseDecimal(%q) succeeded, want error", input)
+		}
+	}
+}
+
+func TestDecimalArithmeticAndRounding(t *testing.T) {
+	t.Parallel()
+
+	if product := mustDecimal(t, "0.1").Mul(mustDecimal(t, "0.2")); product.String() != "0.02" {
+		t.Fatalf("0.1 * 0.2 = %v", product)
+	}
+
+	quotient, err := mustDecimal(t, "2").Div(mustDecimal(t, "3"))
+	if err != nil || quotient.String() != "0.66666667" {
+		t.Fatalf("2 / 3 = %v, %v", quotient, err)
+	}
+
+	rounding := map[valsys.RoundingMode]string{
+		valsys.RoundHalfUp:   "2.35",
+		valsys.RoundHalfEven: "2.34",
+		valsys.RoundDown:     "2.34",
+	}
+
+	for mode, want := range rounding {
+		rounded, err := mustDecimal(t, "2.345").Round(2, mode)
+		if err != nil || rounded.StringFixed(2) != want {
+			t.Fatalf("Round(2.345, %s) = %v, %v, want %s", mode, rounded, err, want)
+		}
+	}
+}
+
+func TestDecimalJSONIsNumber(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal(map[string]valsys.Decimal{"value": mustDecimal(t, "98,7272")})
+	if err != nil || string(data) != `{"value":98.7272}` {
+		t.Fatalf("unexpected JSON: %s, %v", data, err)
+	}
+
+	var decoded struct {
+		Value valsys.Decimal `json:"value"`
+	}
+
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Value.String() != "98.7272" {
+		t.Fatalf("unexpected decode: %v, %v", decoded.Value, err)
+	}
+}
+
+func TestDecimalJSONAcceptsNull(t *testing.T) {
+	t.Parallel()
+
+	decoded := struct {
+		Value valsys.Decimal  `json:"value"`
+		Rate  *valsys.Decimal `json:"rate"`
+	}{Value: mustDecimal(t, "1.5"), Rate: mustRate(t, "2")}
+
+	if err := json.Unmarshal([]byte(`{"value":null,"rate":null}`), &decoded); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	if decoded.Value.String() != "1.5" || decoded.Rate != nil {
+		t.Fatalf("unexpected decode: %v, %v", decoded.Value, decoded.Rate)
+	}
+}
+
+func TestDecimalLargeValues(t *testing.T) {
+	t.Parallel()
+
+	large := mustDecimal(t, "1000000000000.5")
+
+	if sum := large.Add(large); sum.String() != "2000000000001" {
+		t.Fatalf("large + large = %v", sum)
+	}
+
+	product := large.Mul(mustDecimal(t, "1000"))
+	if product.String() != "1000000000000500" {
+		t.Fatalf("large * 1000 = %v", product)
+	}
+
+	quotient, err := product.Div(mustDecimal(t, "0.001"))
+	if err != nil || quotient.StringFixed(2) != "1000000000000500000.00" {
+		t.Fatalf("product / 0.001 = %v, %v", quotient, err)
+	}
+
+	if negative := mustDecimal(t, "-92233720368.54775808"); negative.Sign() >= 0 || negative.Cmp(large) >= 0 {
+		t.Fatalf("unexpected ordering of %v and %v", negative, large)
+	}
+}
+
+func TestDecimalYAMLKeepsDigits(t *testing.T) {
+	t.Parallel()
+
+	value := mustDecimal(t, "123456789012.12345678")
+
+	data, err := yaml.Marshal(map[string]valsys.Decimal{"value": value})
+	if err != nil || string(data) != "value: \"123456789012.12345678\"\n" {
+		t.Fatalf("unexpected YAML: %q, %v", data, err)
+	}
+
+	var decoded map[string]valsys.Decimal
+	if err := yaml.Unmarshal(data, &decoded); err != nil || decoded["value"].Cmp(value) != 0 {
+		t.Fatalf("unexpected decode: %v, %v", decoded["value"], err)
+	}
+}
@@ -0,0 +1,192 @@
+package valsys
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	DefaultFormat    = "json"
+	FullJSONFormat   = "json-full"
+	FullNDJSONFormat = "ndjson-full"
+)
+
+var (
+	errUnknownFmt = errors.New("unknown output format")
+	errMarsCSV    = errors.New("cant marshall csv")
+	errMarsYAML   = errors.New("cant marshall yaml")
+	errMarsXML    = errors.New("cant marshall xml")
+)
+
+type Encoder func(valutes []Valute) ([]byte, error)
+
+type briefValute struct {
+	NumCode  int     `json:"num_code"`
+	CharCode string  `json:"char_code"`
+	Value    Decimal `json:"value"`
+}
+
+type xmlValutes struct {
+	XMLName xml.Name `xml:"Valutes"`
+	Valutes []Valute `xml:"Valute"`
+}
+
+// The default "json" format is brief: it keeps only num_code, char_code and value,
+// as the service always wrote. Every other built-in format writes full records.
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{
+		DefaultFormat:    encodeJSON,
+		FullJSONFormat:   encodeFullJSON,
+		FullNDJSONFormat: encodeNDJSON,
+		"csv":            encodeCSV,
+		"yaml":           encodeYAML,
+		"xml":            encodeXML,
+	}
+)
+
+func RegisterEncoder(format string, encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	encoders[format] = encoder
+}
+
+func Formats() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	formats := make([]string, 0, len(encoders))
+	for format := range encoders {
+		formats = append(formats, format)
+	}
+
+	sort.Strings(formats)
+
+	return formats
+}
+
+func Encode(curs *ValCurs, format string, key SortKey) ([]byte, error) {
+	if format == "" {
+		format = DefaultFormat
+	}
+
+	encodersMu.RLock()
+	encoder, ok := encoders[format]
+	encodersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", errUnknownFmt, format)
+	}
+
+	valutes, err := SortValutes(curs, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return encoder(valutes)
+}
+
+func encodeJSON(valutes []Valute) ([]byte, error) {
+	brief := make([]briefValute, 0, len(valutes))
+
+	for _, value := range valutes {
+		brief = append(brief, briefValute{
+			NumCode:  value.NumCode,
+			CharCode: value.CharCode,
+			Value:    value.Value,
+		})
+	}
+
+	jsonData, err := json.MarshalIndent(brief, "", "  ")
+	if err != nil {
+		return nil, errMarsJSON
+	}
+
+	return jsonData, nil
+}
+
+func encodeFullJSON(valutes []Valute) ([]byte, error) {
+	jsonData, err := json.MarshalIndent(valutes, "", "  ")
+	if err != nil {
+		return nil, errMarsJSON
+	}
+
+	return jsonData, nil
+}
+
+func encodeNDJSON(valutes []Valute) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	encoder := json.NewEncoder(&buffer)
+
+	for _, value := range valutes {
+		if err := encoder.Encode(value); err != nil {
+			return nil, errMarsJSON
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func encodeCSV(valutes []Valute) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	writer := csv.NewWriter(&buffer)
+
+	records := [][]string{{"id", "num_code", "char_code", "nominal", "name", "value", "vunit_rate"}}
+	for _, value := range valutes {
+		records = append(records, []string{
+			value.ID,
+			strconv.Itoa(value.NumCode),
+			value.CharCode,
+			strconv.Itoa(value.Nominal),
+			value.Name,
+			value.Value.String(),
+			optionalDecimal(value.VunitRate),
+		})
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return nil, errMarsCSV
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func encodeYAML(valutes []Valute) ([]byte, error) {
+	yamlData, err := yaml.Marshal(valutes)
+	if err != nil {
+		return nil, errMarsYAML
+	}
+
+	return yamlData, nil
+}
+
+func encodeXML(valutes []Valute) ([]byte, error) {
+	document := xmlValutes{XMLName: xml.Name{Space: "", Local: "Valutes"}, Valutes: valutes}
+
+	xmlData, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, errMarsXML
+	}
+
+	return append([]byte(xml.Header), xmlData...), nil
+}
+
+func optionalDecimal(value *Decimal) string {
+	if value == nil {
+		return ""
+	}
+
+	return value.String()
+}
@@ -0,0 +1,242 @@
+package valsys_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"testing"
+
+	valsys "github.com/faxryzen/task-3/internal/valute_system"
+	"gopkg.in/yaml.v2"
+)
+
+func sampleCurs(t *testing.T) *valsys.ValCurs {
+	t.Helper()
+
+	return &valsys.ValCurs{
+		Date: "02.03.2024",
+		Name: "Foreign Currency Market",
+		Valutes: []valsys.Valute{
+			{
+				ID:        "R01235",
+				NumCode:   840,
+				CharCode:  "USD",
+				Nominal:   1,
+				Name:      "Доллар США",
+				Value:     mustDecimal(t, "91,3336"),
+				VunitRate: mustRate(t, "91,3336"),
+			},
+			{
+				ID:        "R01820",
+				NumCode:   392,
+				CharCode:  "JPY",
+				Nominal:   100,
+				Name:      "Японских иен",
+				Value:     mustDecimal(t, "60,8577"),
+				VunitRate: mustRate(t, "0,608577"),
+			},
+		},
+	}
+}
+
+func sameValutes(left, right []valsys.Valute) bool {
+	if len(left) != len(right) {
+		return false
+	}
+
+	for index := range left {
+		a, b := left[index], right[index]
+		if a.ID != b.ID || a.NumCode != b.NumCode || a.CharCode != b.CharCode || a.Nominal != b.Nominal ||
+			a.Name != b.Name || a.Value.Cmp(b.Value) != 0 || !sameRate(a.VunitRate, b.VunitRate) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameRate(left, right *valsys.Decimal) bool {
+	if left == nil || right == nil {
+		return left == right
+	}
+
+	return left.Cmp(*right) == 0
+}
+
+func decodeCSV(data []byte) ([]valsys.Valute, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	valutes := make([]valsys.Valute, 0, len(records))
+
+	for _, record := range records[1:] {
+		var value valsys.Valute
+
+		value.ID, value.CharCode, value.Name = record[0], record[2], record[4]
+
+		if value.NumCode, err = strconv.Atoi(record[1]); err != nil {
+			return nil, err
+		}
+
+		if value.Nominal, err = strconv.Atoi(record[3]); err != nil {
+			return nil, err
+		}
+
+		if value.Value, err = valsys.ParseDecimal(record[5]); err != nil {
+			return nil, err
+		}
+
+		if record[6] != "" {
+			rate, err := valsys.ParseDecimal(record[6])
+			if err != nil {
+				return nil, err
+			}
+
+			value.VunitRate = &rate
+		}
+
+		valutes = append(valutes, value)
+	}
+
+	return valutes, nil
+}
+
+func decodeNDJSON(data []byte) ([]valsys.Valute, error) {
+	var valutes []valsys.Valute
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	for decoder.More() {
+		var value valsys.Valute
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		valutes = append(valutes, value)
+	}
+
+	return valutes, nil
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	decoders := map[string]func(data []byte) ([]valsys.Valute, error){
+		valsys.FullJSONFormat: func(data []byte) ([]valsys.Valute, error) {
+			var valutes []valsys.Valute
+
+			return valutes, json.Unmarshal(data, &valutes)
+		},
+		valsys.FullNDJSONFormat: decodeNDJSON,
+		"csv":                   decodeCSV,
+		"yaml": func(data []byte) ([]valsys.Valute, error) {
+			var valutes []valsys.Valute
+
+			return valutes, yaml.Unmarshal(data, &valutes)
+		},
+		"xml": func(data []byte) ([]valsys.Valute, error) {
+			var decoded struct {
+				Valutes []valsys.Valute `xml:"Valute"`
+			}
+
+			return decoded.Valutes, xml.Unmarshal(data, &decoded)
+		},
+	}
+
+	curs := sampleCurs(t)
+	curs.Valutes[1].VunitRate = nil
+
+	want, err := valsys.SortValutes(curs, valsys.SortByCode)
+	if err != nil {
+		t.Fatalf("unexpected sort error: %v", err)
+	}
+
+	for format, decode := range decoders {
+		data, err := valsys.Encode(curs, format, valsys.SortByCode)
+		if err != nil {
+			t.Fatalf("%s: unexpected encode error: %v", format, err)
+		}
+
+		got, err := decode(data)
+		if err != nil {
+			t.Fatalf("%s: unexpected decode error: %v\n%s", format, err, data)
+		}
+
+		if !sameValutes(got, want) {
+			t.Fatalf("%s: round trip changed valutes: %+v", format, got)
+		}
+	}
+}
+
+func TestEncodeOmitsMissingUnitRate(t *testing.T) {
+	t.Parallel()
+
+	curs := sampleCurs(t)
+	curs.Valutes[1].VunitRate = nil
+
+	keys := map[string]string{
+		valsys.FullJSONFormat:   `"vunit_rate"`,
+		valsys.FullNDJSONFormat: `"vunit_rate"`,
+		"yaml":                  "vunit_rate:",
+		"xml":                   "<VunitRate>",
+	}
+
+	for format, key := range keys {
+		data, err := valsys.Encode(curs, format, valsys.SortByCode)
+		if err != nil {
+			t.Fatalf("%s: unexpected encode error: %v", format, err)
+		}
+
+		if got := strings.Count(string(data), key); got != 1 {
+			t.Fatalf("%s: expected only USD to carry a unit rate, got %d:\n%s", format, got, data)
+		}
+	}
+
+	data, err := valsys.Encode(curs, "csv", valsys.SortByCode)
+	if err != nil {
+		t.Fatalf("csv: unexpected encode error: %v", err)
+	}
+
+	if !strings.Contains(string(data), "R01820,392,JPY,100,Японских иен,60.8577,\n") {
+		t.Fatalf("csv: expected an empty unit rate for JPY:\n%s", data)
+	}
+}
+
+func TestRegisterEncoderAndUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	valsys.RegisterEncoder("codes", func(valutes []valsys.Valute) ([]byte, error) {
+		var buffer bytes.Buffer
+
+		for _, value := range valutes {
+			buffer.WriteString(value.CharCode + "\n")
+		}
+
+		return buffer.Bytes(), nil
+	})
+
+	data, err := valsys.Encode(sampleCurs(t), "codes", valsys.SortByCode)
+	if err != nil || string(data) != "JPY\nUSD\n" {
+		t.Fatalf("unexpected custom encoding: %q, %v", data, err)
+	}
+
+	found := false
+
+	for _, format := range valsys.Formats() {
+		found = found || format == "codes"
+	}
+
+	if !found {
+		t.Fatalf("registered format is not listed: %v", valsys.Formats())
+	}
+
+	if _, err := valsys.Encode(sampleCurs(t), "toml", valsys.SortByCode); err == nil ||
+		!strings.Contains(err.Error(), "unknown output format") {
+		t.Fatalf("expected unknown format error, got %v", err)
+	}
+}
@@ -1,11 +1,34 @@
 package valsys
 
 type ValCurs struct {
+	Date    string   `xml:"Date,attr"`
+	Name    string   `xml:"name,attr"`
 	Valutes []Valute `xml:"Valute"`
 }
 
 type Valute struct {
-	NumCode  int     `json:"num_code"  xml:"NumCode"`
-	CharCode string  `json:"char_code" xml:"CharCode"`
-	Value    float64 `json:"value"     xml:"Value"`
+	ID        string   `json:"id"                   xml:"ID,attr"             yaml:"id"`
+	NumCode   int      `json:"num_code"             xml:"NumCode"             yaml:"num_code"`
+	CharCode  string   `json:"char_code"            xml:"CharCode"            yaml:"char_code"`
+	Nominal   int      `json:"nominal"              xml:"Nominal"             yaml:"nominal"`
+	Name      string   `json:"name"                 xml:"Name"                yaml:"name"`
+	Value     Decimal  `json:"value"                xml:"Value"               yaml:"value"`
+	VunitRate *Decimal `json:"vunit_rate,omitempty" xml:"VunitRate,omitempty" yaml:"vunit_rate,omitempty"`
+}
+
+func (v Valute) UnitRate() Decimal {
+	if v.VunitRate != nil {
+		return *v.VunitRate
+	}
+
+	if v.Nominal <= 1 {
+		return v.Value
+	}
+
+	rate, err := v.Value.Div(DecimalFromInt(int64(v.Nominal)))
+	if err != nil {
+		return v.Value
+	}
+
+	return rate
 }
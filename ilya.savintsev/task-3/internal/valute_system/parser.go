@@ -31,9 +31,7 @@ func ParseXML(filepath string) (*ValCurs, error) {
 		return nil, errReadXML
 	}
 
-	contentWithDots := bytes.ReplaceAll(content, []byte(","), []byte("."))
-
-	decoder := xml.NewDecoder(bytes.NewReader(contentWithDots))
+	decoder := xml.NewDecoder(bytes.NewReader(content))
 	decoder.CharsetReader = charset.NewReaderLabel
 
 	var curs ValCurs
@@ -0,0 +1,96 @@
+package valsys_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	valsys "github.com/faxryzen/task-3/internal/valute_system"
+)
+
+const cbrSample = `<?xml version="1.0" encoding="UTF-8"?>
+<ValCurs Date="02.03.2024" name="Foreign Currency Market">
+	<Valute ID="R01235">
+		<NumCode>840</NumCode>
+		<CharCode>USD</CharCode>
+		<Nominal>1</Nominal>
+		<Name>Доллар США</Name>
+		<Value>91,3336</Value>
+		<VunitRate>91,3336</VunitRate>
+	</Valute>
+	<Valute ID="R01820">
+		<NumCode>392</NumCode>
+		<CharCode>JPY</CharCode>
+		<Nominal>100</Nominal>
+		<Name>Японских иен</Name>
+		<Value>60,8577</Value>
+	</Valute>
+</ValCurs>`
+
+func TestParseXMLReadsFullRecords(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "rates.xml")
+	if err := os.WriteFile(path, []byte(cbrSample), 0o600); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+
+	curs, err := valsys.ParseXML(path)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	if curs.Date != "02.03.2024" || curs.Name != "Foreign Currency Market" || len(curs.Valutes) != 2 {
+		t.Fatalf("unexpected curs: %+v", curs)
+	}
+
+	usd := curs.Valutes[0]
+	if usd.ID != "R01235" || usd.NumCode != 840 || usd.Nominal != 1 || usd.Name != "Доллар США" ||
+		usd.Value.String() != "91.3336" || usd.VunitRate == nil || usd.VunitRate.String() != "91.3336" {
+		t.Fatalf("unexpected USD record: %+v", usd)
+	}
+
+	jpy := curs.Valutes[1]
+	if jpy.Nominal != 100 || jpy.VunitRate != nil {
+		t.Fatalf("unexpected JPY record: %+v", jpy)
+	}
+}
+
+func unitValute(t *testing.T, nominal int, value, unit string) valsys.Valute {
+	t.Helper()
+
+	var rate *valsys.Decimal
+	if unit != "" {
+		rate = mustRate(t, unit)
+	}
+
+	return valsys.Valute{
+		ID:        "",
+		NumCode:   0,
+		CharCode:  "",
+		Nominal:   nominal,
+		Name:      "",
+		Value:     mustDecimal(t, value),
+		VunitRate: rate,
+	}
+}
+
+func TestUnitRate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		value   valsys.Valute
+		want    string
+		comment string
+	}{
+		{value: unitValute(t, 100, "60,8577", "0,608577"), want: "0.608577", comment: "published unit rate"},
+		{value: unitValute(t, 100, "60,8577", ""), want: "0.608577", comment: "value divided by nominal"},
+		{value: unitValute(t, 0, "91,3336", ""), want: "91.3336", comment: "missing nominal"},
+	}
+
+	for _, current := range cases {
+		if got := current.value.UnitRate().String(); got != current.want {
+			t.Fatalf("%s: UnitRate() = %s, want %s", current.comment, got, current.want)
+		}
+	}
+}
@@ -2,4 +2,7 @@ module github.com/faxryzen/task-5
 
 go 1.22.7
 
-require golang.org/x/sync v0.11.0 // indirect
+require (
+	golang.org/x/sync v0.11.0
+	gopkg.in/yaml.v2 v2.4.0
+)
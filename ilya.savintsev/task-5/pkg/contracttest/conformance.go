@@ -0,0 +1,361 @@
+package contracttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/contract"
+)
+
+const (
+	conformanceTimeout = 2 * time.Second
+	conformanceBuffer  = 8
+	orderedMessages    = 100
+	concurrentSenders  = 8
+	concurrentMessages = 50
+)
+
+var errHandler = errors.New("conformance handler failure")
+
+func RunConformance(t *testing.T, factory contract.Factory) {
+	t.Helper()
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T, factory contract.Factory)
+	}{
+		{name: "UnknownChannel", run: testUnknownChannel},
+		{name: "DecoratorOrdering", run: testDecoratorOrdering},
+		{name: "MultiplexerMerge", run: testMultiplexerMerge},
+		{name: "SeparatorRouting", run: testSeparatorRouting},
+		{name: "ErrorPropagation", run: testErrorPropagation},
+		{name: "Shutdown", run: testShutdown},
+		{name: "ConcurrentSendRecv", run: testConcurrentSendRecv},
+	}
+
+	for _, current := range cases {
+		t.Run(current.name, func(t *testing.T) {
+			t.Parallel()
+
+			current.run(t, factory)
+		})
+	}
+}
+
+func testUnknownChannel(t *testing.T, factory contract.Factory) {
+	conv := factory(conformanceBuffer)
+	conv.RegisterDecorator(prefix, "in", "out")
+	start(t, conv)
+
+	if err := conv.Send("missing", "data"); err == nil || !strings.Contains(err.Error(), contract.ChanNotFound) {
+		t.Fatalf("Send to unknown channel: got %v, want %q", err, contract.ChanNotFound)
+	}
+
+	if _, err := conv.Recv("missing"); err == nil || !strings.Contains(err.Error(), contract.ChanNotFound) {
+		t.Fatalf("Recv from unknown channel: got %v, want %q", err, contract.ChanNotFound)
+	}
+}
+
+func testDecoratorOrdering(t *testing.T, factory contract.Factory) {
+	conv := factory(conformanceBuffer)
+	conv.RegisterDecorator(prefix, "in", "mid")
+	conv.RegisterDecorator(prefix, "mid", "out")
+	start(t, conv)
+
+	go func() {
+		for index := range orderedMessages {
+			if err := conv.Send("in", label(index)); err != nil {
+				t.Errorf("unexpected send error: %v", err)
+
+				return
+			}
+		}
+	}()
+
+	for index := range orderedMessages {
+		if got, want := recv(t, conv, "out"), "!!"+label(index); got != want {
+			t.Fatalf("message %d out of order: got %q, want %q", index, got, want)
+		}
+	}
+}
+
+func testMultiplexerMerge(t *testing.T, factory contract.Factory) {
+	conv := factory(conformanceBuffer)
+	conv.RegisterMultiplexer(merge, []string{"a", "b"}, "out")
+	start(t, conv)
+
+	sent := []string{"a1", "b1", "a2", "b2"}
+	for _, data := range sent {
+		if err := conv.Send(data[:1], data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	got := make([]string, 0, len(sent))
+	for range sent {
+		got = append(got, recv(t, conv, "out"))
+	}
+
+	slices.Sort(got)
+	slices.Sort(sent)
+
+	if !slices.Equal(got, sent) {
+		t.Fatalf("unexpected merged output: got %v, want %v", got, sent)
+	}
+}
+
+func testSeparatorRouting(t *testing.T, factory contract.Factory) {
+	conv := factory(conformanceBuffer)
+	conv.RegisterSeparator(route, "in", []string{"left", "right"})
+	start(t, conv)
+
+	for _, data := range []string{"l1", "r1", "l2", "r2"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	for output, want := range map[string][]string{"left": {"l1", "l2"}, "right": {"r1", "r2"}} {
+		for _, data := range want {
+			if got := recv(t, conv, output); got != data {
+				t.Fatalf("unexpected output on %s: got %q, want %q", output, got, data)
+			}
+		}
+	}
+}
+
+func testErrorPropagation(t *testing.T, factory contract.Factory) {
+	conv := factory(conformanceBuffer)
+	conv.RegisterDecorator(failing, "in", "out")
+
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- conv.Run(context.Background())
+	}()
+
+	if err := conv.Send("in", "data"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, errHandler) {
+			t.Fatalf("Run returned %v, want wrapped handler error", err)
+		}
+	case <-time.After(conformanceTimeout):
+		t.Fatalf("Run did not return after handler failure")
+	}
+}
+
+func testShutdown(t *testing.T, factory contract.Factory) {
+	conv := factory(conformanceBuffer)
+	conv.RegisterDecorator(prefix, "in", "out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- conv.Run(ctx)
+	}()
+
+	if err := conv.Send("in", "data"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if got := recv(t, conv, "out"); got != "!data" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+
+	cancel()
+
+	select {
+	case err := <-stopped:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run returned %v after cancel", err)
+		}
+	case <-time.After(conformanceTimeout):
+		t.Fatalf("Run did not return after cancel")
+	}
+}
+
+func testConcurrentSendRecv(t *testing.T, factory contract.Factory) {
+	conv := factory(conformanceBuffer)
+	conv.RegisterDecorator(prefix, "in", "out")
+	start(t, conv)
+
+	var senders sync.WaitGroup
+
+	for sender := range concurrentSenders {
+		senders.Add(1)
+
+		go func() {
+			defer senders.Done()
+
+			for index := range concurrentMessages {
+				if err := conv.Send("in", fmt.Sprintf("%d-%d", sender, index)); err != nil {
+					t.Errorf("unexpected send error: %v", err)
+
+					return
+				}
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, concurrentSenders*concurrentMessages)
+	for range concurrentSenders * concurrentMessages {
+		seen[recv(t, conv, "out")] = true
+	}
+
+	senders.Wait()
+
+	if len(seen) != concurrentSenders*concurrentMessages {
+		t.Fatalf("unexpected distinct outputs: got %d, want %d", len(seen), concurrentSenders*concurrentMessages)
+	}
+}
+
+func start(t *testing.T, conv contract.Conveyer) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		_ = conv.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+}
+
+func recv(t *testing.T, conv contract.Conveyer, output string) string {
+	t.Helper()
+
+	type result struct {
+		data string
+		err  error
+	}
+
+	received := make(chan result, 1)
+
+	go func() {
+		data, err := conv.Recv(output)
+		received <- result{data: data, err: err}
+	}()
+
+	select {
+	case current := <-received:
+		if current.err != nil {
+			t.Fatalf("unexpected recv error on %s: %v", output, current.err)
+		}
+
+		return current.data
+	case <-time.After(conformanceTimeout):
+		t.Fatalf("nothing received on %s within %v", output, conformanceTimeout)
+
+		return ""
+	}
+}
+
+func prefix(ctx context.Context, input chan string, output chan string) error {
+	for {
+		select {
+		case data, ok := <-input:
+			if !ok {
+				return nil
+			}
+
+			select {
+			case output <- "!" + data:
+			case <-ctx.Done():
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func merge(ctx context.Context, inputs []chan string, output chan string) error {
+	var group sync.WaitGroup
+
+	for _, input := range inputs {
+		group.Add(1)
+
+		go func() {
+			defer group.Done()
+
+			_ = forward(ctx, input, output)
+		}()
+	}
+
+	group.Wait()
+
+	return nil
+}
+
+func forward(ctx context.Context, input chan string, output chan string) error {
+	for {
+		select {
+		case data, ok := <-input:
+			if !ok {
+				return nil
+			}
+
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func route(ctx context.Context, input chan string, outputs []chan string) error {
+	for {
+		select {
+		case data, ok := <-input:
+			if !ok {
+				return nil
+			}
+
+			target := outputs[0]
+			if strings.HasPrefix(data, "r") {
+				target = outputs[1]
+			}
+
+			select {
+			case target <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func failing(ctx context.Context, input chan string, _ chan string) error {
+	select {
+	case <-input:
+		return errHandler
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func label(index int) string {
+	return fmt.Sprintf("%03d", index)
+}
@@ -0,0 +1,19 @@
+package contracttest_test
+
+import (
+	"testing"
+
+	"github.com/faxryzen/task-5/pkg/contract"
+	"github.com/faxryzen/task-5/pkg/contracttest"
+	"github.com/faxryzen/task-5/pkg/conveyer"
+)
+
+var _ contract.Conveyer = (*conveyer.DefaultConveyer)(nil)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	contracttest.RunConformance(t, func(size int) contract.Conveyer {
+		return conveyer.New(size)
+	})
+}
@@ -1,6 +1,183 @@
 package conveyer
 
-func (c *DefaultConveyer) obtainChannel(name string) {
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+type message[T any] struct {
+	data  T
+	seq   uint64
+	trace *trace
+}
+
+type pipe[T any] struct {
+	channel    chan message[T]
+	mu         sync.RWMutex
+	closing    chan struct{}
+	closeOnce  sync.Once
+	writers    int
+	deadLetter bool
+	sent       atomic.Bool
+	received   atomic.Bool
+	journal    *journal
+	backlog    []message[T]
+}
+
+func newPipe[T any](size int) *pipe[T] {
+	var channel chan message[T]
+	if size > 0 {
+		channel = make(chan message[T], size)
+	} else {
+		channel = make(chan message[T])
+	}
+
+	return &pipe[T]{
+		channel:    channel,
+		mu:         sync.RWMutex{},
+		closing:    make(chan struct{}),
+		closeOnce:  sync.Once{},
+		writers:    0,
+		deadLetter: false,
+		sent:       atomic.Bool{},
+		received:   atomic.Bool{},
+		journal:    nil,
+		backlog:    nil,
+	}
+}
+
+func (c *Conveyer[T]) makePipe(name string, size int) *pipe[T] {
+	current := newPipe[T](size)
+
+	config, durable := c.opts.durable[name]
+	if !durable {
+		return current
+	}
+
+	opened, pending, err := openJournal(config)
+	if err != nil {
+		c.journalErrs = append(c.journalErrs, fmt.Errorf("channel %q: %w", name, err))
+
+		return current
+	}
+
+	current.journal = opened
+
+	if opened.skipped > 0 {
+		c.opts.logger.Warn("conveyer: skipped corrupt journal records", "channel", name, "records", opened.skipped)
+	}
+
+	for _, record := range pending {
+		var data T
+		if err := json.Unmarshal(record.data, &data); err != nil {
+			c.journalErrs = append(c.journalErrs, fmt.Errorf("channel %q: %w: %w", name, ErrJournal, err))
+
+			continue
+		}
+
+		current.backlog = append(current.backlog, message[T]{data: data, seq: record.seq, trace: nil})
+	}
+
+	return current
+}
+
+func (p *pipe[T]) wrap(data T) (message[T], error) {
+	if p.journal == nil {
+		return message[T]{data: data, seq: 0, trace: nil}, nil
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return message[T]{data: data, seq: 0, trace: nil}, fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+
+	seq, err := p.journal.put(payload)
+
+	return message[T]{data: data, seq: seq, trace: nil}, err
+}
+
+func (p *pipe[T]) ack(msg message[T]) error {
+	if p.journal == nil || msg.seq == 0 {
+		return nil
+	}
+
+	return p.journal.ack(msg.seq)
+}
+
+func (p *pipe[T]) offer(msg message[T]) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.isClosing() {
+		return false
+	}
+
+	select {
+	case p.channel <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *pipe[T]) close() {
+	p.closeOnce.Do(func() {
+		close(p.closing)
+
+		p.mu.Lock()
+		close(p.channel)
+		p.mu.Unlock()
+	})
+}
+
+func (p *pipe[T]) isClosing() bool {
+	select {
+	case <-p.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Conveyer[T]) RegisterChannel(name string, size int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.channels[name]; exists {
+		return ErrChanExists
+	}
+
+	c.channels[name] = c.makePipe(name, size)
+
+	return nil
+}
+
+func (c *Conveyer[T]) CloseInput(name string) error {
+	c.mu.RLock()
+	current, exists := c.channels[name]
+	owned := exists && (current.writers > 0 || current.deadLetter)
+	c.mu.RUnlock()
+
+	if !exists {
+		return ErrChanNotFound
+	}
+
+	if owned {
+		return ErrChanOwned
+	}
+
+	current.close()
+
+	return nil
+}
+
+func (c *Conveyer[T]) obtainChannel(name string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -9,37 +186,121 @@ func (c *DefaultConveyer) obtainChannel(name string) {
 		return
 	}
 
-	var channel chan string
-	if c.bufferSize > 0 {
-		channel = make(chan string, c.bufferSize)
-	} else {
-		channel = make(chan string)
-	}
-
-	c.channels[name] = channel
+	c.channels[name] = c.makePipe(name, c.bufferSize)
 }
 
-func (c *DefaultConveyer) getChannel(name string) (chan string, error) {
+func (c *Conveyer[T]) getPipe(name string) (*pipe[T], error) {
 	c.mu.RLock()
-	channel, exists := c.channels[name]
+	current, exists := c.channels[name]
 	c.mu.RUnlock()
 
 	if !exists {
 		return nil, ErrChanNotFound
 	}
 
-	return channel, nil
+	return current, nil
 }
 
-func (c *DefaultConveyer) closeAllChannels() {
-	c.closeOnce.Do(func() {
-		c.mu.Lock()
-		defer c.mu.Unlock()
+func (c *Conveyer[T]) getPipes(names []string) ([]*pipe[T], error) {
+	pipes := make([]*pipe[T], 0, len(names))
 
-		for _, channel := range c.channels {
-			if channel != nil {
-				close(channel)
-			}
+	for _, name := range names {
+		current, err := c.getPipe(name)
+		if err != nil {
+			return nil, err
 		}
+
+		pipes = append(pipes, current)
+	}
+
+	return pipes, nil
+}
+
+func (c *Conveyer[T]) closeInputs() {
+	c.stopOnce.Do(func() {
+		close(c.stopping)
 	})
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, current := range c.channels {
+		if current.writers == 0 && !current.deadLetter {
+			current.close()
+		}
+	}
+}
+
+func (c *Conveyer[T]) closeAllChannels() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, current := range c.channels {
+		current.close()
+	}
+}
+
+func (c *Conveyer[T]) replayBacklog(ctx context.Context, group *errgroup.Group) {
+	for _, current := range c.channels {
+		backlog := current.backlog
+		current.backlog = nil
+
+		if len(backlog) == 0 {
+			continue
+		}
+
+		group.Go(func() error {
+			current.mu.RLock()
+			defer current.mu.RUnlock()
+
+			for _, msg := range backlog {
+				if _, err := current.wait(ctx, msg, nil); err != nil {
+					break
+				}
+			}
+
+			return nil
+		})
+	}
+}
+
+func (c *Conveyer[T]) flushJournals(ctx context.Context) func() {
+	var flushers sync.WaitGroup
+
+	for _, current := range c.channels {
+		if current.journal == nil {
+			continue
+		}
+
+		if config := current.journal.config; config.Fsync != FsyncInterval || config.FsyncInterval <= 0 {
+			continue
+		}
+
+		flushers.Add(1)
+
+		go func() {
+			defer flushers.Done()
+
+			current.journal.flushEvery(ctx)
+		}()
+	}
+
+	return flushers.Wait
+}
+
+func (c *Conveyer[T]) Close() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	errs := make([]error, 0)
+
+	for _, name := range sortedKeys(c.channels) {
+		if current := c.channels[name]; current.journal != nil {
+			if err := current.journal.close(); err != nil {
+				errs = append(errs, fmt.Errorf("channel %q: %w", name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
 }
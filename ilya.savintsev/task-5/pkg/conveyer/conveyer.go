@@ -4,245 +4,525 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
+	"github.com/faxryzen/task-5/pkg/clock"
 	"golang.org/x/sync/errgroup"
 )
 
 var (
-	ErrChanNotFound = errors.New("chan not found")
-	ErrTimeout      = errors.New("timeout")
-	ErrFullChannel  = errors.New("channel is full")
+	ErrChanNotFound    = errors.New("chan not found")
+	ErrChanExists      = errors.New("chan already exists")
+	ErrChanOwned       = errors.New("chan is written by a stage")
+	ErrChannelClosed   = errors.New("channel is closed")
+	ErrAlreadyRan      = errors.New("conveyer already ran")
+	ErrTimeout         = errors.New("timeout")
+	ErrFullChannel     = errors.New("channel is full")
+	ErrUnknownSendMode = errors.New("unknown send mode")
+	ErrStopped         = errors.New("message dropped on hard stop")
 )
 
 const (
 	undefinedStr = "undefined"
 	timeoutTime  = 100
+
+	defaultDrainTimeout = 5 * time.Second
+)
+
+const (
+	kindDecorator   = "decorator"
+	kindMultiplexer = "multiplexer"
+	kindSeparator   = "separator"
+	kindFilter      = "filter"
+	kindMap         = "map"
+	kindBatch       = "batch"
+	kindWindow      = "window"
+)
+
+type (
+	DecoratorFunc[T any]   func(ctx context.Context, input chan T, output chan T) error
+	MultiplexerFunc[T any] func(ctx context.Context, inputs []chan T, output chan T) error
+	SeparatorFunc[T any]   func(ctx context.Context, input chan T, outputs []chan T) error
 )
 
-type specDecorator struct {
-	fn     func(context.Context, chan string, chan string) error
-	input  string
-	output string
+type stageFunc[T any] func(ctx context.Context, inputs []chan T, outputs []chan T) error
+
+type stage[T any] struct {
+	stageOptions
+
+	kind     string
+	inputs   []string
+	outputs  []string
+	fn       stageFunc[T]
+	metrics  *stageMetrics
+	released bool
+}
+
+type StageOption func(*stageOptions)
+
+type stageOptions struct {
+	name      string
+	policy    ErrorPolicy
+	workers   int
+	ordered   bool
+	rateLimit RateLimit
+	breaker   BreakerConfig
+}
+
+func WithStageName(name string) StageOption {
+	return func(opts *stageOptions) {
+		opts.name = name
+	}
+}
+
+func stageName(kind string, inputs, outputs []string) string {
+	switch kind {
+	case kindMultiplexer:
+		return fmt.Sprintf("%s %v -> %s", kind, inputs, outputs[0])
+	case kindSeparator:
+		return fmt.Sprintf("%s %s -> %v", kind, inputs[0], outputs)
+	default:
+		return fmt.Sprintf("%s %s -> %s", kind, inputs[0], outputs[0])
+	}
+}
+
+func (s stage[T]) deadLetters() []string {
+	var names []string
+
+	if s.policy.DeadLetter != "" {
+		names = append(names, s.policy.DeadLetter)
+	}
+
+	if s.breaker.Failures > 0 && s.breaker.Mode == BreakerDeadLetter && s.breaker.DeadLetter != "" {
+		names = append(names, s.breaker.DeadLetter)
+	}
+
+	return names
+}
+
+func stateless(kind string) bool {
+	return kind == kindDecorator || kind == kindFilter || kind == kindMap
+}
+
+func (s stage[T]) workerCount() int {
+	if stateless(s.kind) && s.workers > 1 {
+		return s.workers
+	}
+
+	return 1
+}
+
+type Option func(*options)
+
+type options struct {
+	drainTimeout      time.Duration
+	sendPolicy        SendPolicy
+	undefinedOnClosed bool
+	hotReload         bool
+	durable           map[string]DurableConfig
+	tracer            SpanExporter
+	clock             clock.Clock
+	events            func(StageEvent)
+	logger            *slog.Logger
+}
+
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.drainTimeout = timeout
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(opts *options) {
+		opts.logger = logger
+	}
 }
 
-type specMultiplexer struct {
-	fn     func(context.Context, []chan string, chan string) error
-	inputs []string
-	output string
+func WithClock(clk clock.Clock) Option {
+	return func(opts *options) {
+		opts.clock = clk
+	}
 }
 
-type specSeparator struct {
-	fn      func(context.Context, chan string, []chan string) error
-	input   string
-	outputs []string
+type Conveyer[T any] struct {
+	mu          sync.RWMutex
+	channels    map[string]*pipe[T]
+	bufferSize  int
+	opts        options
+	stages      []stage[T]
+	inputs      []string
+	outputs     []string
+	late        []string
+	rejected    []Problem
+	failures    failureLog[T]
+	running     bool
+	hardStop    context.CancelFunc
+	group       *errgroup.Group
+	stageCtx    context.Context
+	hot         sync.WaitGroup
+	hotErr      error
+	stopping    chan struct{}
+	stopOnce    sync.Once
+	runs        map[string]*stageRun[T]
+	journalErrs []error
+	closedValue *T
+	done        chan struct{}
 }
 
-type DefaultConveyer struct {
-	mu           sync.RWMutex
-	closeOnce    sync.Once
-	channels     map[string]chan string
-	bufferSize   int
-	decorators   []specDecorator
-	multiplexers []specMultiplexer
-	separators   []specSeparator
+type DefaultConveyer = Conveyer[string]
+
+func New(size int, opts ...Option) *DefaultConveyer {
+	return NewConveyer[string](size, opts...)
 }
 
-func New(size int) *DefaultConveyer {
-	return &DefaultConveyer{
-		mu:           sync.RWMutex{},
-		closeOnce:    sync.Once{},
-		channels:     make(map[string]chan string),
-		bufferSize:   size,
-		decorators:   []specDecorator{},
-		multiplexers: []specMultiplexer{},
-		separators:   []specSeparator{},
+func NewConveyer[T any](size int, opts ...Option) *Conveyer[T] {
+	settings := options{
+		drainTimeout:      defaultDrainTimeout,
+		sendPolicy:        defaultSendPolicy(),
+		undefinedOnClosed: false,
+		hotReload:         false,
+		durable:           make(map[string]DurableConfig),
+		tracer:            nil,
+		clock:             clock.Real(),
+		events:            nil,
+		logger:            slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(&settings)
 	}
+
+	conv := &Conveyer[T]{
+		mu:          sync.RWMutex{},
+		channels:    make(map[string]*pipe[T]),
+		bufferSize:  size,
+		opts:        settings,
+		stages:      []stage[T]{},
+		inputs:      []string{},
+		outputs:     []string{},
+		late:        []string{},
+		rejected:    []Problem{},
+		failures:    failureLog[T]{mu: sync.Mutex{}, items: []Failure[T]{}},
+		running:     false,
+		hardStop:    nil,
+		group:       nil,
+		stageCtx:    nil,
+		hot:         sync.WaitGroup{},
+		hotErr:      nil,
+		stopping:    make(chan struct{}),
+		stopOnce:    sync.Once{},
+		runs:        make(map[string]*stageRun[T]),
+		journalErrs: nil,
+		closedValue: nil,
+		done:        make(chan struct{}),
+	}
+
+	if settings.undefinedOnClosed {
+		if undefined, ok := any(undefinedStr).(T); ok {
+			conv.closedValue = &undefined
+		} else {
+			conv.reject(Problem{
+				Kind:    ProblemUnsupportedOption,
+				Channel: "",
+				Detail:  "undefined on closed needs string payloads",
+			})
+		}
+	}
+
+	return conv
 }
 
-func (c *DefaultConveyer) RegisterDecorator(
-	handlerFunc func(context.Context, chan string, chan string) error,
+func (c *Conveyer[T]) RegisterDecorator(
+	handlerFunc func(context.Context, chan T, chan T) error,
 	input, output string,
 ) {
-	c.obtainChannel(input)
-	c.obtainChannel(output)
-
-	c.mu.Lock()
-	c.decorators = append(c.decorators, specDecorator{
-		fn:     handlerFunc,
-		input:  input,
-		output: output,
-	})
-	c.mu.Unlock()
+	c.RegisterDecoratorWithOptions(handlerFunc, input, output)
 }
 
-func (c *DefaultConveyer) RegisterMultiplexer(
-	handlerFunc func(context.Context, []chan string, chan string) error,
+func (c *Conveyer[T]) RegisterMultiplexer(
+	handlerFunc func(context.Context, []chan T, chan T) error,
 	inputs []string,
 	output string,
 ) {
-	for _, name := range inputs {
-		c.obtainChannel(name)
-	}
+	c.RegisterMultiplexerWithOptions(handlerFunc, inputs, output)
+}
+
+func (c *Conveyer[T]) RegisterSeparator(
+	handlerFunc func(context.Context, chan T, []chan T) error,
+	input string,
+	outputs []string,
+) {
+	c.RegisterSeparatorWithOptions(handlerFunc, input, outputs)
+}
 
-	c.obtainChannel(output)
+func (c *Conveyer[T]) RegisterDecoratorWithOptions(
+	handlerFunc DecoratorFunc[T],
+	input, output string,
+	opts ...StageOption,
+) {
+	c.registerOneToOne(kindDecorator, handlerFunc, input, output, opts)
+}
 
-	c.mu.Lock()
-	c.multiplexers = append(c.multiplexers, specMultiplexer{
-		fn:     handlerFunc,
-		inputs: inputs,
-		output: output,
-	})
-	c.mu.Unlock()
+func (c *Conveyer[T]) RegisterMultiplexerWithOptions(
+	handlerFunc MultiplexerFunc[T],
+	inputs []string,
+	output string,
+	opts ...StageOption,
+) {
+	c.addStage(kindMultiplexer, inputs, []string{output},
+		func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+			return handlerFunc(ctx, inputs, outputs[0])
+		}, opts)
 }
 
-func (c *DefaultConveyer) RegisterSeparator(
-	handlerFunc func(context.Context, chan string, []chan string) error,
+func (c *Conveyer[T]) RegisterSeparatorWithOptions(
+	handlerFunc SeparatorFunc[T],
 	input string,
 	outputs []string,
+	opts ...StageOption,
 ) {
-	c.obtainChannel(input)
+	c.addStage(kindSeparator, []string{input}, outputs,
+		func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+			return handlerFunc(ctx, inputs[0], outputs)
+		}, opts)
+}
 
-	for _, name := range outputs {
-		c.obtainChannel(name)
+func (c *Conveyer[T]) addStage(
+	kind string,
+	inputs, outputs []string,
+	handlerFunc stageFunc[T],
+	opts []StageOption,
+) {
+	settings := stageOptions{
+		name:      stageName(kind, inputs, outputs),
+		policy:    FailFastPolicy(),
+		workers:   1,
+		ordered:   false,
+		rateLimit: RateLimit{Rate: 0, Burst: 0},
+		breaker:   BreakerConfig{Failures: 0, Cooldown: 0, Mode: BreakerBuffer, DeadLetter: ""},
 	}
 
-	c.mu.Lock()
-	c.separators = append(c.separators, specSeparator{
-		fn:      handlerFunc,
-		input:   input,
-		outputs: outputs,
-	})
-	c.mu.Unlock()
-}
+	for _, opt := range opts {
+		opt(&settings)
+	}
 
-func (c *DefaultConveyer) Run(ctx context.Context) error {
-	defer c.closeAllChannels()
+	if settings.breaker.Failures > 0 && (settings.policy.Action == FailFast || !perMessage(kind)) {
+		c.reject(Problem{
+			Kind:    ProblemUnsupportedOption,
+			Channel: "",
+			Detail:  settings.name + " needs a per-message stage whose error policy keeps it running to use a breaker",
+		})
 
-	group, groupCtx := errgroup.WithContext(ctx)
+		return
+	}
 
-	c.runDecorators(group, groupCtx)
-	c.runMultiplexers(group, groupCtx)
-	c.runSeparators(group, groupCtx)
+	current := stage[T]{
+		stageOptions: settings,
+		kind:         kind,
+		inputs:       inputs,
+		outputs:      outputs,
+		fn:           handlerFunc,
+		metrics:      newStageMetrics(),
+		released:     false,
+	}
 
-	if err := group.Wait(); err != nil {
-		return fmt.Errorf("conveyer finished with error: %w", err)
+	for _, name := range current.inputs {
+		c.obtainChannel(name)
 	}
 
-	return nil
-}
+	for _, name := range current.outputs {
+		c.obtainChannel(name)
+	}
 
-func (c *DefaultConveyer) Send(input string, data string) error {
-	channel, err := c.getChannel(input)
-	if err != nil {
-		return err
+	for _, name := range current.deadLetters() {
+		c.obtainChannel(name)
 	}
 
-	select {
-	case channel <- data:
-		return nil
-	case <-time.After(timeoutTime * time.Millisecond):
-		return ErrTimeout
-	default:
-		return ErrFullChannel
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running && (!c.opts.hotReload || c.group == nil) {
+		c.late = append(c.late, current.name)
+		c.opts.logger.Error("conveyer: stage registered after run is ignored",
+			"stage", current.name, "hot_reload", c.opts.hotReload)
+
+		return
 	}
-}
 
-func (c *DefaultConveyer) Recv(output string) (string, error) {
-	channel, err := c.getChannel(output)
-	if err != nil {
-		return "", err
+	for _, name := range current.outputs {
+		c.channels[name].writers++
 	}
 
-	data, ok := <-channel
-	if !ok {
-		return undefinedStr, nil
+	for _, name := range current.deadLetters() {
+		c.channels[name].deadLetter = true
 	}
 
-	return data, nil
-}
+	c.stages = append(c.stages, current)
 
-func (c *DefaultConveyer) runDecorators(group *errgroup.Group, ctx context.Context) {
-	c.mu.RLock()
-	decoratorList := append([]specDecorator(nil), c.decorators...)
-	c.mu.RUnlock()
+	if c.running {
+		c.hot.Add(1)
 
-	for _, decoratorSpec := range decoratorList {
-		current := decoratorSpec
+		go c.runHot(c.stageCtx, current)
+	}
+}
 
-		group.Go(func() error {
-			inputChannel, err := c.getChannel(current.input)
-			if err != nil {
-				return err
-			}
+func (c *Conveyer[T]) runHot(ctx context.Context, current stage[T]) {
+	defer c.hot.Done()
 
-			outputChannel, err := c.getChannel(current.output)
-			if err != nil {
-				return err
-			}
+	if err := c.runStage(ctx, current); err != nil {
+		c.mu.Lock()
+		if c.hotErr == nil {
+			c.hotErr = err
+		}
+		c.mu.Unlock()
 
-			return current.fn(ctx, inputChannel, outputChannel)
-		})
+		c.stop()
 	}
 }
 
-func (c *DefaultConveyer) runMultiplexers(group *errgroup.Group, ctx context.Context) {
+func (c *Conveyer[T]) Run(ctx context.Context) error {
+	if err := c.validateForRun(); err != nil {
+		return err
+	}
+
 	c.mu.RLock()
-	multiplexerList := append([]specMultiplexer(nil), c.multiplexers...)
+	journalErr := errors.Join(c.journalErrs...)
 	c.mu.RUnlock()
 
-	for _, multiplexer := range multiplexerList {
-		current := multiplexer
+	if journalErr != nil {
+		return journalErr
+	}
 
-		group.Go(func() error {
-			inputChannels := make([]chan string, 0, len(current.inputs))
+	stageCtx, hardStop := context.WithCancel(context.WithoutCancel(ctx))
+	defer hardStop()
 
-			for _, name := range current.inputs {
-				channel, err := c.getChannel(name)
-				if err != nil {
-					return err
-				}
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+
+		return ErrAlreadyRan
+	}
 
-				inputChannels = append(inputChannels, channel)
-			}
+	group, groupCtx := errgroup.WithContext(stageCtx)
 
-			outputChannel, err := c.getChannel(current.output)
-			if err != nil {
-				return err
-			}
+	c.running = true
+	c.hardStop = hardStop
+	c.group, c.stageCtx = group, stageCtx
 
-			return current.fn(ctx, inputChannels, outputChannel)
+	for _, current := range c.stages {
+		group.Go(func() error {
+			return c.runStage(groupCtx, current)
 		})
 	}
+
+	c.replayBacklog(groupCtx, group)
+
+	flushCtx, stopFlushing := context.WithCancel(context.Background())
+	flushed := c.flushJournals(flushCtx)
+	c.mu.Unlock()
+
+	defer close(c.done)
+	defer c.closeAllChannels()
+	defer func() {
+		stopFlushing()
+		flushed()
+	}()
+
+	finished := make(chan struct{})
+	defer close(finished)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.drain(hardStop)
+		case <-finished:
+		}
+	}()
+
+	err := group.Wait()
+	if err != nil {
+		hardStop()
+	}
+
+	if c.opts.hotReload {
+		select {
+		case <-c.stopping:
+		case <-stageCtx.Done():
+		}
+	}
+
+	c.mu.Lock()
+	c.group = nil
+	c.mu.Unlock()
+
+	c.hot.Wait()
+
+	if err == nil {
+		c.mu.RLock()
+		err = c.hotErr
+		c.mu.RUnlock()
+	}
+
+	if err != nil {
+		return fmt.Errorf("conveyer finished with error: %w", err)
+	}
+
+	return nil
 }
 
-func (c *DefaultConveyer) runSeparators(group *errgroup.Group, ctx context.Context) {
+func (c *Conveyer[T]) Shutdown(ctx context.Context) error {
 	c.mu.RLock()
-	separatorList := append([]specSeparator(nil), c.separators...)
+	running := c.running
 	c.mu.RUnlock()
 
-	for _, separator := range separatorList {
-		current := separator
+	c.closeInputs()
 
-		group.Go(func() error {
-			inputChannel, err := c.getChannel(current.input)
-			if err != nil {
-				return err
-			}
+	if !running {
+		return nil
+	}
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		c.stop()
+		<-c.done
 
-			outputChannels := make([]chan string, 0, len(current.outputs))
+		return fmt.Errorf("shutdown interrupted: %w", ctx.Err())
+	}
+}
 
-			for _, name := range current.outputs {
-				channel, err := c.getChannel(name)
-				if err != nil {
-					return err
-				}
+func (c *Conveyer[T]) drain(hardStop context.CancelFunc) {
+	c.closeInputs()
 
-				outputChannels = append(outputChannels, channel)
-			}
+	if c.opts.drainTimeout <= 0 {
+		hardStop()
 
-			return current.fn(ctx, inputChannel, outputChannels)
-		})
+		return
 	}
+
+	timer := time.AfterFunc(c.opts.drainTimeout, hardStop)
+
+	go func() {
+		<-c.done
+		timer.Stop()
+	}()
+}
+
+func (c *Conveyer[T]) stop() {
+	c.mu.RLock()
+	hardStop := c.hardStop
+	c.mu.RUnlock()
+
+	if hardStop != nil {
+		hardStop()
+	}
+}
+
+func (c *Conveyer[T]) Send(input string, data T) error {
+	_, err := c.SendWithPolicy(context.Background(), input, data, c.opts.sendPolicy)
+
+	return err
 }
@@ -0,0 +1,304 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+type baselineConveyer interface {
+	RegisterDecorator(fn func(context.Context, chan string, chan string) error, input, output string)
+	RegisterMultiplexer(fn func(context.Context, []chan string, chan string) error, inputs []string, output string)
+	RegisterSeparator(fn func(context.Context, chan string, []chan string) error, input string, outputs []string)
+	Run(ctx context.Context) error
+	Send(input string, data string) error
+	Recv(output string) (string, error)
+}
+
+var _ baselineConveyer = conveyer.New(0)
+
+func TestShutdownDrainsInFlight(t *testing.T) {
+	t.Parallel()
+
+	const messages = 5
+
+	conv := conveyer.New(messages)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "mid")
+	conv.RegisterSeparator(handlers.SeparatorFunc, "mid", []string{"left", "right"})
+	conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"left", "right"}, "out")
+
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- conv.Run(context.Background())
+	}()
+
+	for index := range messages {
+		if err := conv.Send("in", strconv.Itoa(index)); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := conv.Shutdown(ctx); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	if err := <-runErr; err != nil {
+		t.Fatalf("unexpected run error: %v", err)
+	}
+
+	for range messages {
+		if _, err := conv.Recv("out"); err != nil {
+			t.Fatalf("unexpected recv error: %v", err)
+		}
+	}
+
+	if err := conv.Send("in", "late"); !errors.Is(err, conveyer.ErrChannelClosed) {
+		t.Fatalf("expected ErrChannelClosed, got %v", err)
+	}
+}
+
+func TestCancelDrainsInFlight(t *testing.T) {
+	t.Parallel()
+
+	const messages = 5
+
+	slow := func(ctx context.Context, input chan string, output chan string) error {
+		for data := range input {
+			time.Sleep(5 * time.Millisecond)
+
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	conv := conveyer.New(messages)
+	conv.RegisterDecorator(slow, "in", "out")
+
+	for index := range messages {
+		if err := conv.Send("in", strconv.Itoa(index)); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- conv.Run(ctx)
+	}()
+
+	cancel()
+
+	if err := <-runErr; err != nil {
+		t.Fatalf("unexpected run error: %v", err)
+	}
+
+	for index := range messages {
+		if data, err := conv.Recv("out"); err != nil || data != strconv.Itoa(index) {
+			t.Fatalf("message %d: got %q, %v", index, data, err)
+		}
+	}
+
+	if failures := conv.Failures(); len(failures) != 0 {
+		t.Fatalf("messages were dropped on cancel: %+v", failures)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(0)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	go func() {
+		_ = conv.Run(context.Background())
+	}()
+
+	deadline := time.Now().Add(time.Second)
+
+	for err := conv.Send("in", "stuck"); err != nil; err = conv.Send("in", "stuck") {
+		if time.Now().After(deadline) {
+			t.Fatalf("decorator did not take the message: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := conv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+}
+
+func TestRecvContextAndClosedChannel(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1)
+	conv.DeclareInputs("in")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := conv.RecvContext(ctx, "in"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+
+	if err := conv.CloseInput("in"); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	if _, err := conv.Recv("in"); !errors.Is(err, conveyer.ErrChannelClosed) {
+		t.Fatalf("expected ErrChannelClosed, got %v", err)
+	}
+
+	legacy := conveyer.New(1, conveyer.WithUndefinedOnClosed())
+	legacy.DeclareInputs("in")
+
+	if err := legacy.CloseInput("in"); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	if data, err := legacy.Recv("in"); err != nil || data != "undefined" {
+		t.Fatalf("expected legacy undefined, got %q %v", data, err)
+	}
+}
+
+type order struct {
+	ID     int
+	Amount float64
+	Tags   []string
+}
+
+func TestStructPayloads(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.NewConveyer[order](4)
+	conv.RegisterDecorator(doubleOrder, "in", "out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = conv.Run(ctx)
+	}()
+
+	for id := range 4 {
+		if err := conv.Send("in", order{ID: id, Amount: float64(id), Tags: nil}); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	seen := make(map[int]bool)
+
+	for range 4 {
+		item, err := conv.Recv("out")
+		if err != nil {
+			t.Fatalf("unexpected recv error: %v", err)
+		}
+
+		if item.Amount != float64(2*item.ID) || len(item.Tags) != 1 || item.Tags[0] != "doubled" {
+			t.Fatalf("unexpected item: %+v", item)
+		}
+
+		seen[item.ID] = true
+	}
+
+	if len(seen) != 4 {
+		t.Fatalf("unexpected ids: %v", seen)
+	}
+
+	closed := conveyer.NewConveyer[order](1, conveyer.WithUndefinedOnClosed())
+
+	var validationErr *conveyer.ValidationError
+	if err := closed.Validate(); !errors.As(err, &validationErr) ||
+		validationErr.Problems[0].Kind != conveyer.ProblemUnsupportedOption {
+		t.Fatalf("expected undefined on closed to be rejected for a typed conveyer, got %v", err)
+	}
+
+	if err := closed.Run(context.Background()); !errors.Is(err, conveyer.ErrInvalidGraph) {
+		t.Fatalf("expected run to refuse the option, got %v", err)
+	}
+}
+
+func doubleOrder(ctx context.Context, input chan order, output chan order) error {
+	for {
+		select {
+		case item, ok := <-input:
+			if !ok {
+				return nil
+			}
+
+			item.Amount *= 2
+			item.Tags = append(item.Tags, "doubled")
+
+			select {
+			case output <- item:
+			case <-ctx.Done():
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func TestLeastLoadedSeparatorSeesChannelDepth(t *testing.T) {
+	t.Parallel()
+
+	const routed = 4
+
+	conv := conveyer.New(routed)
+	conv.RegisterSeparator(handlers.LeastLoadedSeparator[string](conv.Depths([]string{"a", "b"})),
+		"in", []string{"a", "b"})
+	runConveyer(t, conv)
+
+	if err := conv.Send("in", "first"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	waitDepth(t, conv, "a", 1)
+
+	for range routed {
+		if err := conv.Send("in", "routed"); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+
+		if data := recvAll(t, conv, "b", 1); data[0] != "routed" {
+			t.Fatalf("unexpected message on b: %q", data[0])
+		}
+	}
+
+	waitDepth(t, conv, "a", 1)
+}
+
+func waitDepth(t *testing.T, conv *conveyer.DefaultConveyer, name string, depth int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		for _, channel := range conv.Metrics().Channels {
+			if channel.Name == name && channel.Depth == depth {
+				return
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("channel %q did not reach depth %d: %+v", name, depth, conv.Metrics().Channels)
+}
@@ -0,0 +1,219 @@
+package conveyer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/clock"
+)
+
+const maxFailures = 1024
+
+type ErrorAction int
+
+const (
+	FailFast ErrorAction = iota
+	SkipMessage
+	RetryMessage
+	DeadLetter
+)
+
+type ErrorPolicy struct {
+	Action     ErrorAction
+	Retries    int
+	Backoff    time.Duration
+	DeadLetter string
+}
+
+type Failure[T any] struct {
+	Stage   string
+	Payload T
+	Err     error
+	Time    time.Time
+}
+
+type retry[T any] struct {
+	timer  clock.Timer
+	failed *delivery[T]
+}
+
+type letter[T any] struct {
+	target *pipe[T]
+	msg    message[T]
+	failed *delivery[T]
+}
+
+type failureLog[T any] struct {
+	mu    sync.Mutex
+	items []Failure[T]
+}
+
+func FailFastPolicy() ErrorPolicy {
+	return ErrorPolicy{Action: FailFast, Retries: 0, Backoff: 0, DeadLetter: ""}
+}
+
+func SkipPolicy() ErrorPolicy {
+	return ErrorPolicy{Action: SkipMessage, Retries: 0, Backoff: 0, DeadLetter: ""}
+}
+
+func RetryPolicy(retries int, backoff time.Duration) ErrorPolicy {
+	return ErrorPolicy{Action: RetryMessage, Retries: retries, Backoff: backoff, DeadLetter: ""}
+}
+
+func DeadLetterPolicy(channel string) ErrorPolicy {
+	return ErrorPolicy{Action: DeadLetter, Retries: 0, Backoff: 0, DeadLetter: channel}
+}
+
+func RetryThenDeadLetterPolicy(retries int, backoff time.Duration, channel string) ErrorPolicy {
+	return ErrorPolicy{Action: RetryMessage, Retries: retries, Backoff: backoff, DeadLetter: channel}
+}
+
+func WithErrorPolicy(policy ErrorPolicy) StageOption {
+	return func(opts *stageOptions) {
+		opts.policy = policy
+	}
+}
+
+func (c *Conveyer[T]) Failures() []Failure[T] {
+	c.failures.mu.Lock()
+	defer c.failures.mu.Unlock()
+
+	return append([]Failure[T](nil), c.failures.items...)
+}
+
+func (l *failureLog[T]) add(failure Failure[T]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.items) == maxFailures {
+		l.items = l.items[1:]
+	}
+
+	l.items = append(l.items, failure)
+}
+
+func (c *Conveyer[T]) handleFailure(run *stageRun[T], failed *delivery[T], cause error) error {
+	policy := run.stage.policy
+
+	c.failures.add(Failure[T]{
+		Stage:   run.stage.name,
+		Payload: failed.msg.data,
+		Err:     cause,
+		Time:    c.opts.clock.Now(),
+	})
+
+	switch policy.Action {
+	case SkipMessage:
+		return nil
+	case RetryMessage:
+		if failed.attempts < policy.Retries {
+			run.schedule(failed, policy.Backoff<<failed.attempts)
+
+			return nil
+		}
+
+		if policy.DeadLetter == "" {
+			return fmt.Errorf("%s: retries exhausted: %w", run.stage.name, cause)
+		}
+
+		return run.post(policy.DeadLetter, failed)
+	case DeadLetter:
+		return run.post(policy.DeadLetter, failed)
+	default:
+		return cause
+	}
+}
+
+func (c *Conveyer[T]) dropped(stage string, data T) {
+	c.failures.add(Failure[T]{
+		Stage:   stage,
+		Payload: data,
+		Err:     ErrStopped,
+		Time:    c.opts.clock.Now(),
+	})
+}
+
+func (r *stageRun[T]) schedule(failed *delivery[T], backoff time.Duration) {
+	failed.complete = false
+	r.retrying = append(r.retrying, retry[T]{timer: r.clock.NewTimer(backoff), failed: failed})
+}
+
+func (r *stageRun[T]) retried(ctx context.Context, index int) {
+	failed := r.retrying[index].failed
+	r.retrying = append(r.retrying[:index:index], r.retrying[index+1:]...)
+
+	failed.attempts++
+	r.requeue(failed)
+	r.advance(ctx)
+}
+
+func (r *stageRun[T]) awaitingRetry(input int) bool {
+	for _, pending := range r.retrying {
+		if pending.failed.input == input {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *stageRun[T]) post(name string, failed *delivery[T]) error {
+	target, err := r.conv.getPipe(name)
+	if err != nil {
+		return err
+	}
+
+	if target.isClosing() {
+		return fmt.Errorf("dead letter %q: %w", name, ErrChannelClosed)
+	}
+
+	msg, err := target.wrap(failed.msg.data)
+	if err != nil {
+		return err
+	}
+
+	failed.complete = false
+	r.letters = append(r.letters, letter[T]{target: target, msg: msg, failed: failed})
+
+	return nil
+}
+
+func (r *stageRun[T]) posted(ctx context.Context) {
+	sent := r.letters[0].failed
+	r.letters = r.letters[1:]
+
+	sent.complete = true
+	r.settle(sent)
+
+	if r.ordered {
+		r.completed(ctx, nil)
+	}
+
+	r.advance(ctx)
+}
+
+func (r *stageRun[T]) abandon() {
+	for _, pending := range r.retrying {
+		pending.timer.Stop()
+
+		if !r.ordered && !pending.failed.source.offer(pending.failed.msg) {
+			r.conv.dropped(r.stage.name, pending.failed.msg.data)
+		}
+	}
+
+	r.retrying = nil
+
+	for _, pending := range r.letters {
+		if !pending.target.offer(pending.msg) {
+			_ = pending.target.ack(pending.msg)
+			r.conv.dropped(r.stage.name, pending.msg.data)
+			pending.failed.unsaved = true
+		}
+
+		r.settle(pending.failed)
+	}
+
+	r.letters = nil
+}
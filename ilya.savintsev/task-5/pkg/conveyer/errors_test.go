@@ -0,0 +1,299 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func TestSkipPolicy(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4)
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.SkipPolicy()))
+	runConveyer(t, conv)
+
+	for _, data := range []string{"a", "no decorator", "b"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	got := recvAll(t, conv, "out", 2)
+	if got[0] != "decorated: a" || got[1] != "decorated: b" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	failures := conv.Failures()
+	if len(failures) != 1 || failures[0].Payload != "no decorator" ||
+		!errors.Is(failures[0].Err, handlers.ErrNoDecorator) {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+}
+
+func TestDeadLetterPolicy(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4)
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.DeadLetterPolicy("dead")))
+	runConveyer(t, conv)
+
+	for _, data := range []string{"no decorator", "a"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if got := recvAll(t, conv, "dead", 1); got[0] != "no decorator" {
+		t.Fatalf("unexpected dead letter: %v", got)
+	}
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "decorated: a" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+}
+
+var errFlaky = errors.New("flaky")
+
+func TestRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	flaky := func(ctx context.Context, input chan string, output chan string) error {
+		for data := range input {
+			attempts++
+			if attempts < 3 {
+				return errFlaky
+			}
+
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	conv := conveyer.New(4)
+	conv.RegisterDecoratorWithOptions(flaky, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.RetryPolicy(3, time.Millisecond)))
+	runConveyer(t, conv)
+
+	if err := conv.Send("in", "payload"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "payload" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	if failures := conv.Failures(); len(failures) != 2 {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+}
+
+func TestFailFastPolicy(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- conv.Run(context.Background())
+	}()
+
+	if err := conv.Send("in", "no decorator"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if err := <-runErr; !errors.Is(err, handlers.ErrNoDecorator) {
+		t.Fatalf("expected ErrNoDecorator, got %v", err)
+	}
+}
+
+func TestRetryRerunsOnlyTheFailedMessage(t *testing.T) {
+	t.Parallel()
+
+	calls := make(map[string]int)
+	flaky := func(ctx context.Context, input chan string, output chan string) error {
+		for data := range input {
+			calls[data]++
+			if data == "bad" && calls[data] == 1 {
+				return errFlaky
+			}
+
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	conv := conveyer.New(4)
+	conv.RegisterDecoratorWithOptions(flaky, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.RetryPolicy(1, time.Millisecond)))
+	runConveyer(t, conv)
+
+	for _, data := range []string{"a", "bad", "c"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if got := recvAll(t, conv, "out", 3); got[0] != "a" || got[1] != "bad" || got[2] != "c" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	if calls["a"] != 1 || calls["bad"] != 2 || calls["c"] != 1 {
+		t.Fatalf("unexpected handler calls: %v", calls)
+	}
+
+	failures := conv.Failures()
+	if len(failures) != 1 || failures[0].Payload != "bad" {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+}
+
+func TestMessagePolicyNeedsOneToOneStage(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4)
+	conv.RegisterBatch(2, time.Second, func(batch []string) string { return strings.Join(batch, ",") },
+		"in", "out", conveyer.WithErrorPolicy(conveyer.SkipPolicy()))
+	conv.RegisterMultiplexerWithOptions(handlers.MultiplexerFunc, []string{"x", "y"}, "merged",
+		conveyer.WithErrorPolicy(conveyer.DeadLetterPolicy("dead")))
+	conv.RegisterSeparatorWithOptions(handlers.SeparatorFunc, "split", []string{"left", "right"},
+		conveyer.WithErrorPolicy(conveyer.SkipPolicy()))
+
+	var validationErr *conveyer.ValidationError
+	if err := conv.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	count := 0
+
+	for _, problem := range validationErr.Problems {
+		if problem.Kind == conveyer.ProblemMessagePolicy {
+			count++
+		}
+	}
+
+	if count != 2 {
+		t.Fatalf("unexpected problems: %+v", validationErr.Problems)
+	}
+}
+
+func TestDeadLetterInterruptedByStopIsRecorded(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4, conveyer.WithDrainTimeout(20*time.Millisecond))
+	if err := conv.RegisterChannel("dead", 0); err != nil {
+		t.Fatalf("unexpected register error: %v", err)
+	}
+
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.DeadLetterPolicy("dead")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- conv.Run(ctx)
+	}()
+
+	if err := conv.Send("in", "no decorator"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	for len(conv.Failures()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-runErr; err != nil {
+		t.Fatalf("unexpected run error: %v", err)
+	}
+
+	failures := conv.Failures()
+	if len(failures) != 2 || failures[1].Payload != "no decorator" || !errors.Is(failures[1].Err, conveyer.ErrStopped) {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+}
+
+func TestPendingRetryInterruptedByStopIsRecorded(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4, conveyer.WithDrainTimeout(20*time.Millisecond))
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.RetryPolicy(1, time.Hour)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- conv.Run(ctx)
+	}()
+
+	if err := conv.Send("in", "no decorator"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	for len(conv.Failures()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-runErr; err != nil {
+		t.Fatalf("unexpected run error: %v", err)
+	}
+
+	failures := conv.Failures()
+	if len(failures) != 2 || failures[1].Payload != "no decorator" || !errors.Is(failures[1].Err, conveyer.ErrStopped) {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+}
+
+func TestRetryThenDeadLetterPolicy(t *testing.T) {
+	t.Parallel()
+
+	broken := func(_ context.Context, input chan string, _ chan string) error {
+		for range input {
+			return errFlaky
+		}
+
+		return nil
+	}
+
+	conv := conveyer.New(4)
+	conv.RegisterDecoratorWithOptions(broken, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.RetryThenDeadLetterPolicy(2, time.Millisecond, "dead")))
+	runConveyer(t, conv)
+
+	if err := conv.Send("in", "payload"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if got := recvAll(t, conv, "dead", 1); got[0] != "payload" {
+		t.Fatalf("unexpected dead letter: %v", got)
+	}
+
+	if failures := conv.Failures(); len(failures) != 3 {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+}
@@ -0,0 +1,211 @@
+package conveyer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownGraphFormat = errors.New("unknown graph format")
+
+type GraphFormat int
+
+const (
+	GraphDOT GraphFormat = iota
+	GraphMermaid
+)
+
+type GraphOption func(*graphOptions)
+
+type graphOptions struct {
+	depths bool
+}
+
+type graphChannel struct {
+	id         string
+	name       string
+	capacity   int
+	depth      int
+	deadLetter bool
+}
+
+type graphStage struct {
+	id          string
+	name        string
+	kind        string
+	workers     int
+	inputs      []string
+	outputs     []string
+	deadLetters []string
+}
+
+func WithQueueDepths() GraphOption {
+	return func(opts *graphOptions) {
+		opts.depths = true
+	}
+}
+
+func (c *Conveyer[T]) ExportGraph(writer io.Writer, format GraphFormat, opts ...GraphOption) error {
+	settings := graphOptions{depths: false}
+
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	channels, stages := c.graph()
+
+	var builder strings.Builder
+
+	switch format {
+	case GraphDOT:
+		writeDOT(&builder, channels, stages, settings)
+	case GraphMermaid:
+		writeMermaid(&builder, channels, stages, settings)
+	default:
+		return fmt.Errorf("%w: %d", ErrUnknownGraphFormat, format)
+	}
+
+	if _, err := io.WriteString(writer, builder.String()); err != nil {
+		return fmt.Errorf("write graph: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Conveyer[T]) graph() (map[string]graphChannel, []graphStage) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	channels := make(map[string]graphChannel, len(c.channels))
+
+	for index, name := range sortedKeys(c.channels) {
+		current := c.channels[name]
+		channels[name] = graphChannel{
+			id:         fmt.Sprintf("c%d", index),
+			name:       name,
+			capacity:   cap(current.channel),
+			depth:      len(current.channel),
+			deadLetter: current.deadLetter,
+		}
+	}
+
+	stages := make([]graphStage, 0, len(c.stages))
+
+	for index, current := range c.stages {
+		stages = append(stages, graphStage{
+			id:          fmt.Sprintf("s%d", index),
+			name:        current.name,
+			kind:        current.kind,
+			workers:     current.workers,
+			inputs:      current.inputs,
+			outputs:     current.outputs,
+			deadLetters: current.deadLetters(),
+		})
+	}
+
+	return channels, stages
+}
+
+func channelLabel(channel graphChannel, settings graphOptions, newline string) string {
+	label := fmt.Sprintf("%s%sbuffer %d", channel.name, newline, channel.capacity)
+
+	if settings.depths {
+		label += fmt.Sprintf("%sdepth %d/%d", newline, channel.depth, channel.capacity)
+	}
+
+	if channel.deadLetter {
+		label += newline + "dead letter"
+	}
+
+	return label
+}
+
+func stageLabel(current graphStage, newline string) string {
+	label := current.name + newline + current.kind
+
+	if current.workers > 1 {
+		label += fmt.Sprintf(", %d workers", current.workers)
+	}
+
+	return label
+}
+
+func writeDOT(builder *strings.Builder, channels map[string]graphChannel, stages []graphStage, settings graphOptions) {
+	builder.WriteString("digraph conveyer {\n\trankdir=LR;\n")
+
+	for _, name := range sortedKeys(channels) {
+		channel := channels[name]
+
+		style := "solid"
+		if channel.deadLetter {
+			style = "dashed"
+		}
+
+		fmt.Fprintf(builder, "\t%s [shape=ellipse, style=%s, label=%s];\n",
+			channel.id, style, strconv.Quote(channelLabel(channel, settings, "\n")))
+	}
+
+	for _, current := range stages {
+		fmt.Fprintf(builder, "\t%s [shape=box, label=%s];\n", current.id, strconv.Quote(stageLabel(current, "\n")))
+	}
+
+	for _, current := range stages {
+		for _, input := range current.inputs {
+			fmt.Fprintf(builder, "\t%s -> %s;\n", channels[input].id, current.id)
+		}
+
+		for _, output := range current.outputs {
+			fmt.Fprintf(builder, "\t%s -> %s;\n", current.id, channels[output].id)
+		}
+
+		for _, dead := range current.deadLetters {
+			fmt.Fprintf(builder, "\t%s -> %s [style=dashed];\n", current.id, channels[dead].id)
+		}
+	}
+
+	builder.WriteString("}\n")
+}
+
+func writeMermaid(
+	builder *strings.Builder,
+	channels map[string]graphChannel,
+	stages []graphStage,
+	settings graphOptions,
+) {
+	builder.WriteString("flowchart LR\n")
+
+	for _, name := range sortedKeys(channels) {
+		channel := channels[name]
+		fmt.Fprintf(builder, "\t%s([\"%s\"])\n", channel.id, mermaidEscape(channelLabel(channel, settings, "<br/>")))
+
+		if channel.deadLetter {
+			fmt.Fprintf(builder, "\tclass %s deadLetter\n", channel.id)
+		}
+	}
+
+	for _, current := range stages {
+		fmt.Fprintf(builder, "\t%s[\"%s\"]\n", current.id, mermaidEscape(stageLabel(current, "<br/>")))
+	}
+
+	for _, current := range stages {
+		for _, input := range current.inputs {
+			fmt.Fprintf(builder, "\t%s --> %s\n", channels[input].id, current.id)
+		}
+
+		for _, output := range current.outputs {
+			fmt.Fprintf(builder, "\t%s --> %s\n", current.id, channels[output].id)
+		}
+
+		for _, dead := range current.deadLetters {
+			fmt.Fprintf(builder, "\t%s -.-> %s\n", current.id, channels[dead].id)
+		}
+	}
+
+	builder.WriteString("\tclassDef deadLetter stroke-dasharray: 5 5\n")
+}
+
+func mermaidEscape(label string) string {
+	return strings.ReplaceAll(label, "\"", "#quot;")
+}
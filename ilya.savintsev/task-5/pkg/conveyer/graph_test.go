@@ -0,0 +1,93 @@
+package conveyer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func graphConveyer(t *testing.T) *conveyer.DefaultConveyer {
+	t.Helper()
+
+	conv := conveyer.New(4)
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "mid",
+		conveyer.WithStageName("prefix"),
+		conveyer.WithErrorPolicy(conveyer.DeadLetterPolicy("dead")))
+	conv.RegisterSeparator(handlers.SeparatorFunc, "mid", []string{"left", "right"})
+
+	if err := conv.Send("in", "queued"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	return conv
+}
+
+func TestExportGraphDOT(t *testing.T) {
+	t.Parallel()
+
+	var builder strings.Builder
+
+	if err := graphConveyer(t).ExportGraph(&builder, conveyer.GraphDOT, conveyer.WithQueueDepths()); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+
+	got := builder.String()
+
+	for _, want := range []string{
+		"digraph conveyer {",
+		`c1 [shape=ellipse, style=solid, label="in\nbuffer 4\ndepth 1/4"];`,
+		`c0 [shape=ellipse, style=dashed, label="dead\nbuffer 4\ndepth 0/4\ndead letter"];`,
+		`s0 [shape=box, label="prefix\ndecorator"];`,
+		"c1 -> s0;",
+		"s0 -> c3;",
+		"s0 -> c0 [style=dashed];",
+		"c3 -> s1;",
+		"s1 -> c2;",
+		"s1 -> c4;",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("DOT output misses %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestExportGraphMermaid(t *testing.T) {
+	t.Parallel()
+
+	var builder strings.Builder
+
+	if err := graphConveyer(t).ExportGraph(&builder, conveyer.GraphMermaid); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+
+	got := builder.String()
+
+	for _, want := range []string{
+		"flowchart LR",
+		`c1(["in<br/>buffer 4"])`,
+		"class c0 deadLetter",
+		`s1["separator mid -> [left right]<br/>separator"]`,
+		"c3 --> s1",
+		"s0 -.-> c0",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("Mermaid output misses %q:\n%s", want, got)
+		}
+	}
+
+	if strings.Contains(got, "depth") {
+		t.Fatalf("unexpected queue depths without option:\n%s", got)
+	}
+}
+
+func TestExportGraphUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	err := conveyer.New(1).ExportGraph(&strings.Builder{}, conveyer.GraphFormat(42))
+	if !errors.Is(err, conveyer.ErrUnknownGraphFormat) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
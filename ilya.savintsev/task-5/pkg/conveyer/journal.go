@@ -0,0 +1,398 @@
+package conveyer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSegmentSize = 4 << 20
+	journalDirPerm     = 0o755
+	journalFilePerm    = 0o600
+	segmentExt         = ".wal"
+)
+
+var ErrJournal = errors.New("durable channel journal failure")
+
+type FsyncPolicy int
+
+const (
+	FsyncAlways FsyncPolicy = iota
+	FsyncInterval
+	FsyncNever
+)
+
+type DurableConfig struct {
+	Dir           string
+	Fsync         FsyncPolicy
+	FsyncInterval time.Duration
+	SegmentSize   int64
+}
+
+type journalRecord struct {
+	Op   string          `json:"op"`
+	Seq  uint64          `json:"seq"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type segment struct {
+	path   string
+	index  int
+	maxSeq uint64
+}
+
+type journal struct {
+	mu       sync.Mutex
+	config   DurableConfig
+	file     *os.File
+	size     int64
+	segments []segment
+	unacked  map[uint64]struct{}
+	nextSeq  uint64
+	dirty    bool
+	skipped  int
+}
+
+type pendingRecord struct {
+	seq  uint64
+	data json.RawMessage
+}
+
+func WithDurableChannel(name string, config DurableConfig) Option {
+	return func(opts *options) {
+		opts.durable[name] = config
+	}
+}
+
+func openJournal(config DurableConfig) (*journal, []pendingRecord, error) {
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = defaultSegmentSize
+	}
+
+	if err := os.MkdirAll(config.Dir, journalDirPerm); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+
+	segments, err := listSegments(config.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := &journal{
+		mu:       sync.Mutex{},
+		config:   config,
+		file:     nil,
+		size:     0,
+		segments: nil,
+		unacked:  make(map[uint64]struct{}),
+		nextSeq:  1,
+		dirty:    false,
+		skipped:  0,
+	}
+
+	puts := make(map[uint64]json.RawMessage)
+
+	for _, item := range segments {
+		if err := current.load(item.path, puts); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pending := make([]pendingRecord, 0, len(puts))
+	for seq, data := range puts {
+		pending = append(pending, pendingRecord{seq: seq, data: data})
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].seq < pending[j].seq
+	})
+
+	if err := current.compact(segments, pending); err != nil {
+		return nil, nil, err
+	}
+
+	return current, pending, nil
+}
+
+func (j *journal) load(path string, puts map[uint64]json.RawMessage) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), int(j.config.SegmentSize)+bufio.MaxScanTokenSize)
+
+	for scanner.Scan() {
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			j.skipped++
+
+			continue
+		}
+
+		switch record.Op {
+		case "put":
+			puts[record.Seq] = record.Data
+		case "ack":
+			delete(puts, record.Seq)
+		}
+
+		if record.Seq >= j.nextSeq {
+			j.nextSeq = record.Seq + 1
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrJournal, path, err)
+	}
+
+	return nil
+}
+
+func (j *journal) compact(old []segment, pending []pendingRecord) error {
+	next := 0
+	if len(old) > 0 {
+		next = old[len(old)-1].index + 1
+	}
+
+	if err := j.rotate(next); err != nil {
+		return err
+	}
+
+	for _, record := range pending {
+		if err := j.write(journalRecord{Op: "put", Seq: record.seq, Data: record.data}); err != nil {
+			return err
+		}
+
+		j.unacked[record.seq] = struct{}{}
+	}
+
+	if err := j.sync(true); err != nil {
+		return err
+	}
+
+	for _, item := range old {
+		if err := os.Remove(item.path); err != nil {
+			return fmt.Errorf("%w: %w", ErrJournal, err)
+		}
+	}
+
+	return nil
+}
+
+func (j *journal) put(data json.RawMessage) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	seq := j.nextSeq
+	j.nextSeq++
+
+	if err := j.write(journalRecord{Op: "put", Seq: seq, Data: data}); err != nil {
+		return 0, err
+	}
+
+	j.unacked[seq] = struct{}{}
+
+	return seq, j.sync(false)
+}
+
+func (j *journal) ack(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.unacked[seq]; !ok {
+		return nil
+	}
+
+	delete(j.unacked, seq)
+
+	if err := j.write(journalRecord{Op: "ack", Seq: seq, Data: nil}); err != nil {
+		return err
+	}
+
+	return j.sync(false)
+}
+
+func (j *journal) flushEvery(ctx context.Context) {
+	ticker := time.NewTicker(j.config.FsyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.flushDirty()
+		case <-ctx.Done():
+			j.flushDirty()
+
+			return
+		}
+	}
+}
+
+func (j *journal) flushDirty() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file != nil && j.dirty {
+		_ = j.sync(true)
+	}
+}
+
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+
+	syncErr := j.sync(true)
+	closeErr := j.file.Close()
+	j.file = nil
+
+	if syncErr != nil {
+		return syncErr
+	}
+
+	if closeErr != nil {
+		return fmt.Errorf("%w: %w", ErrJournal, closeErr)
+	}
+
+	return nil
+}
+
+func (j *journal) write(record journalRecord) error {
+	if j.file == nil {
+		return fmt.Errorf("%w: journal is closed", ErrJournal)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+
+	line = append(line, '\n')
+
+	if j.size > 0 && j.size+int64(len(line)) > j.config.SegmentSize {
+		if err := j.rotate(j.segments[len(j.segments)-1].index + 1); err != nil {
+			return err
+		}
+	}
+
+	written, err := j.file.Write(line)
+	j.size += int64(written)
+	j.dirty = true
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+
+	if record.Op == "put" {
+		j.segments[len(j.segments)-1].maxSeq = record.Seq
+	}
+
+	return nil
+}
+
+func (j *journal) rotate(index int) error {
+	if j.file != nil {
+		if err := j.sync(true); err != nil {
+			return err
+		}
+
+		if err := j.file.Close(); err != nil {
+			return fmt.Errorf("%w: %w", ErrJournal, err)
+		}
+	}
+
+	path := filepath.Join(j.config.Dir, fmt.Sprintf("%08d%s", index, segmentExt))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, journalFilePerm)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+
+	j.file = file
+	j.size = 0
+	j.segments = append(j.segments, segment{path: path, index: index, maxSeq: 0})
+
+	return j.dropAcked()
+}
+
+func (j *journal) dropAcked() error {
+	oldest := j.nextSeq
+
+	for seq := range j.unacked {
+		if seq < oldest {
+			oldest = seq
+		}
+	}
+
+	dropped := 0
+
+	for dropped < len(j.segments)-1 && j.segments[dropped].maxSeq < oldest {
+		if err := os.Remove(j.segments[dropped].path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %w", ErrJournal, err)
+		}
+
+		dropped++
+	}
+
+	j.segments = j.segments[dropped:]
+
+	return nil
+}
+
+func (j *journal) sync(force bool) error {
+	if !force && j.config.Fsync != FsyncAlways {
+		return nil
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+
+	j.dirty = false
+
+	return nil
+}
+
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+
+	segments := make([]segment, 0, len(entries))
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		var index int
+		if _, err := fmt.Sscanf(strings.TrimSuffix(name, segmentExt), "%d", &index); err != nil {
+			continue
+		}
+
+		segments = append(segments, segment{path: filepath.Join(dir, name), index: index, maxSeq: 0})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].index < segments[j].index
+	})
+
+	return segments, nil
+}
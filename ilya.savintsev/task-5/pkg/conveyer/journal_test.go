@@ -0,0 +1,338 @@
+package conveyer_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func newDurable(t *testing.T, dir string) *conveyer.DefaultConveyer {
+	t.Helper()
+
+	conv := conveyer.New(4,
+		conveyer.WithDurableChannel("in", conveyer.DurableConfig{
+			Dir:           filepath.Join(dir, "in"),
+			Fsync:         conveyer.FsyncAlways,
+			FsyncInterval: 0,
+			SegmentSize:   64,
+		}),
+		conveyer.WithDurableChannel("out", conveyer.DurableConfig{
+			Dir:           filepath.Join(dir, "out"),
+			Fsync:         conveyer.FsyncNever,
+			FsyncInterval: 0,
+			SegmentSize:   0,
+		}),
+	)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	t.Cleanup(func() {
+		if err := conv.Close(); err != nil {
+			t.Errorf("unexpected close error: %v", err)
+		}
+	})
+
+	return conv
+}
+
+func runUntilStopped(t *testing.T, conv *conveyer.DefaultConveyer) func() {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- conv.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+
+		select {
+		case err := <-stopped:
+			if err != nil {
+				t.Fatalf("unexpected run error: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("conveyer did not stop")
+		}
+	}
+}
+
+func TestDurableChannelResumesUnacked(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	first := newDurable(t, dir)
+	stop := runUntilStopped(t, first)
+
+	for _, data := range []string{"a", "b", "c"} {
+		if err := first.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if got := recvAll(t, first, "out", 1); got[0] != "decorated: a" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	stop()
+
+	second := newDurable(t, dir)
+	stop = runUntilStopped(t, second)
+
+	got := recvAll(t, second, "out", 2)
+	sort.Strings(got)
+
+	if got[0] != "decorated: b" || got[1] != "decorated: c" {
+		t.Fatalf("unexpected resumed output: %v", got)
+	}
+
+	stop()
+
+	third := newDurable(t, dir)
+	stop = runUntilStopped(t, third)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if data, err := third.RecvContext(ctx, "out"); err == nil {
+		t.Fatalf("expected no redelivery, got %q", data)
+	}
+}
+
+func TestDurableChannelKeepsUnsentInput(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	first := newDurable(t, dir)
+	if err := first.Send("in", "pending"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if err := first.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	second := newDurable(t, dir)
+	stop := runUntilStopped(t, second)
+	defer stop()
+
+	if got := recvAll(t, second, "out", 1); got[0] != "decorated: pending" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+}
+
+func TestDurableChannelAcksAfterShutdown(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	first := newDurable(t, dir)
+	stop := runUntilStopped(t, first)
+
+	if err := first.Send("in", "a"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	waitDepth(t, first, "out", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := first.Shutdown(ctx); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	stop()
+
+	if got := recvAll(t, first, "out", 1); got[0] != "decorated: a" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	second := newDurable(t, dir)
+	stop = runUntilStopped(t, second)
+	defer stop()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+
+	if data, err := second.RecvContext(waitCtx, "out"); err == nil {
+		t.Fatalf("expected no redelivery, got %q", data)
+	}
+}
+
+func TestDurableChannelSkipsCorruptRecords(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	segment := filepath.Join(dir, "in", "00000000.wal")
+
+	if err := os.MkdirAll(filepath.Dir(segment), 0o755); err != nil {
+		t.Fatalf("unexpected mkdir error: %v", err)
+	}
+
+	records := `{"op":"put","seq":1,"data":"a"}` + "\n" + `{"op":"put",` + "\n" + `{"op":"put","seq":2,"data":"b"}` + "\n"
+	if err := os.WriteFile(segment, []byte(records), 0o600); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+
+	var logs bytes.Buffer
+
+	conv := conveyer.New(4,
+		conveyer.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		conveyer.WithDurableChannel("in", conveyer.DurableConfig{
+			Dir:           filepath.Join(dir, "in"),
+			Fsync:         conveyer.FsyncInterval,
+			FsyncInterval: time.Millisecond,
+			SegmentSize:   0,
+		}),
+	)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	t.Cleanup(func() { _ = conv.Close() })
+
+	stop := runUntilStopped(t, conv)
+	defer stop()
+
+	got := recvAll(t, conv, "out", 2)
+	if got[0] != "decorated: a" || got[1] != "decorated: b" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	if !strings.Contains(logs.String(), "skipped corrupt journal records") ||
+		!strings.Contains(logs.String(), "records=1") {
+		t.Fatalf("corrupt record was not reported:\n%s", logs.String())
+	}
+}
+
+func newDurableMultiplexer(
+	t *testing.T,
+	dir string,
+	handler func(context.Context, []chan string, chan string) error,
+) *conveyer.DefaultConveyer {
+	t.Helper()
+
+	conv := conveyer.New(4,
+		conveyer.WithDrainTimeout(10*time.Millisecond),
+		conveyer.WithDurableChannel("in", conveyer.DurableConfig{
+			Dir:           filepath.Join(dir, "in"),
+			Fsync:         conveyer.FsyncAlways,
+			FsyncInterval: 0,
+			SegmentSize:   0,
+		}),
+	)
+	conv.RegisterMultiplexer(handler, []string{"in"}, "out")
+	t.Cleanup(func() {
+		if err := conv.Close(); err != nil {
+			t.Errorf("unexpected close error: %v", err)
+		}
+	})
+
+	return conv
+}
+
+func TestDurableChannelReplaysMessagesHeldByMultiplexer(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	held := make(chan string, 2)
+
+	holding := func(ctx context.Context, inputs []chan string, _ chan string) error {
+		for range 2 {
+			held <- <-inputs[0]
+		}
+
+		<-ctx.Done()
+
+		return nil
+	}
+
+	first := newDurableMultiplexer(t, dir, holding)
+	stop := runUntilStopped(t, first)
+
+	for _, data := range []string{"x", "y"} {
+		if err := first.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	<-held
+	<-held
+	stop()
+
+	second := newDurableMultiplexer(t, dir, handlers.MergeMultiplexer[string]())
+	stop = runUntilStopped(t, second)
+	defer stop()
+
+	got := recvAll(t, second, "out", 2)
+	sort.Strings(got)
+
+	if got[0] != "x" || got[1] != "y" {
+		t.Fatalf("held messages were not replayed: %v", got)
+	}
+}
+
+// Not parallel: it counts journal flusher goroutines, which parallel tests would also start.
+func TestDurableFlusherStopsWithRun(t *testing.T) {
+	conv := conveyer.New(4, conveyer.WithDurableChannel("in", conveyer.DurableConfig{
+		Dir:           filepath.Join(t.TempDir(), "in"),
+		Fsync:         conveyer.FsyncInterval,
+		FsyncInterval: time.Hour,
+		SegmentSize:   0,
+	}))
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	stop := runUntilStopped(t, conv)
+	waitGoroutines(t, "flushEvery", 1)
+	stop()
+	waitGoroutines(t, "flushEvery", 0)
+
+	if err := conv.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+}
+
+func waitGoroutines(t *testing.T, function string, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		var dump bytes.Buffer
+
+		_ = pprof.Lookup("goroutine").WriteTo(&dump, 2)
+
+		got := 0
+
+		for _, stack := range strings.Split(dump.String(), "\n\n") {
+			if strings.Contains(stack, function) {
+				got++
+			}
+		}
+
+		if got == want {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d goroutines running %s, got %d", want, function, got)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,269 @@
+package conveyer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/clock"
+)
+
+type EventKind int
+
+const (
+	EventThrottled EventKind = iota
+	EventUnthrottled
+	EventBreakerOpen
+	EventBreakerHalfOpen
+	EventBreakerClosed
+)
+
+type BreakerMode int
+
+const (
+	BreakerBuffer BreakerMode = iota
+	BreakerDeadLetter
+)
+
+type StageEvent struct {
+	Stage string
+	Kind  EventKind
+	Time  time.Time
+}
+
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+type BreakerConfig struct {
+	Failures   int
+	Cooldown   time.Duration
+	Mode       BreakerMode
+	DeadLetter string
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type gateDecision int
+
+const (
+	gatePass gateDecision = iota
+	gateWait
+	gateDivert
+)
+
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     RateLimit
+	clock     clock.Clock
+	tokens    float64
+	last      time.Time
+	throttled bool
+	notify    func(EventKind)
+}
+
+type breaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	clock    clock.Clock
+	state    breakerState
+	failures int
+	since    time.Time
+	trial    bool
+	changed  chan struct{}
+	notify   func(EventKind)
+}
+
+func (k EventKind) String() string {
+	switch k {
+	case EventThrottled:
+		return "throttled"
+	case EventUnthrottled:
+		return "unthrottled"
+	case EventBreakerOpen:
+		return "breaker open"
+	case EventBreakerHalfOpen:
+		return "breaker half-open"
+	case EventBreakerClosed:
+		return "breaker closed"
+	default:
+		return undefinedStr
+	}
+}
+
+func WithStageEvents(handler func(StageEvent)) Option {
+	return func(opts *options) {
+		opts.events = handler
+	}
+}
+
+func WithRateLimit(rate float64, burst int) StageOption {
+	return func(opts *stageOptions) {
+		opts.rateLimit = RateLimit{Rate: rate, Burst: max(burst, 1)}
+	}
+}
+
+func WithCircuitBreaker(config BreakerConfig) StageOption {
+	return func(opts *stageOptions) {
+		opts.breaker = config
+	}
+}
+
+func (c *Conveyer[T]) notifier(stage string) func(EventKind) {
+	return func(kind EventKind) {
+		if c.opts.events != nil {
+			c.opts.events(StageEvent{Stage: stage, Kind: kind, Time: c.opts.clock.Now()})
+		}
+	}
+}
+
+func newRateLimiter(limit RateLimit, clk clock.Clock, notify func(EventKind)) *rateLimiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		mu:        sync.Mutex{},
+		limit:     limit,
+		clock:     clk,
+		tokens:    float64(limit.Burst),
+		last:      clk.Now(),
+		throttled: false,
+		notify:    notify,
+	}
+}
+
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.tokens = min(float64(l.limit.Burst), l.tokens+now.Sub(l.last).Seconds()*l.limit.Rate)
+	l.last = now
+	l.tokens--
+
+	throttled := l.tokens < 0
+	if throttled != l.throttled {
+		l.throttled = throttled
+
+		if throttled {
+			l.notify(EventThrottled)
+		} else {
+			l.notify(EventUnthrottled)
+		}
+	}
+
+	if !throttled {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.limit.Rate * float64(time.Second))
+}
+
+func newBreaker(config BreakerConfig, clk clock.Clock, notify func(EventKind)) *breaker {
+	if config.Failures <= 0 {
+		return nil
+	}
+
+	return &breaker{
+		mu:       sync.Mutex{},
+		config:   config,
+		clock:    clk,
+		state:    breakerClosed,
+		failures: 0,
+		since:    clk.Now(),
+		trial:    false,
+		changed:  make(chan struct{}),
+		notify:   notify,
+	}
+}
+
+func (b *breaker) allow() (gateDecision, <-chan struct{}, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elapsed := b.clock.Now().Sub(b.since)
+
+	switch b.state {
+	case breakerClosed:
+		return gatePass, nil, 0
+	case breakerOpen:
+		if elapsed < b.config.Cooldown {
+			if b.config.Mode == BreakerDeadLetter {
+				return gateDivert, nil, 0
+			}
+
+			return gateWait, b.changed, b.config.Cooldown - elapsed
+		}
+
+		b.transition(breakerHalfOpen, EventBreakerHalfOpen)
+
+		return gatePass, nil, 0
+	default:
+		if !b.trial {
+			return gatePass, nil, 0
+		}
+
+		if elapsed < b.config.Cooldown {
+			return gateWait, b.changed, b.config.Cooldown - elapsed
+		}
+
+		b.reset()
+
+		return gatePass, nil, 0
+	}
+}
+
+func (b *breaker) handed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.trial = true
+		b.since = b.clock.Now()
+	}
+}
+
+func (b *breaker) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.reset()
+}
+
+func (b *breaker) failed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.config.Failures) {
+		b.trial = false
+		b.transition(breakerOpen, EventBreakerOpen)
+	}
+}
+
+func (b *breaker) reset() {
+	b.failures = 0
+
+	if b.state == breakerHalfOpen && b.trial {
+		b.trial = false
+		b.transition(breakerClosed, EventBreakerClosed)
+	}
+}
+
+func (b *breaker) transition(state breakerState, event EventKind) {
+	b.state = state
+	b.since = b.clock.Now()
+
+	close(b.changed)
+	b.changed = make(chan struct{})
+
+	b.notify(event)
+}
@@ -0,0 +1,178 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/conveyertest"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func expectEvent(t *testing.T, events chan conveyer.EventKind, want conveyer.EventKind) {
+	t.Helper()
+
+	select {
+	case got := <-events:
+		if got != want {
+			t.Fatalf("unexpected event: got %v, want %v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("event %v was not emitted", want)
+	}
+}
+
+func expectNothing(t *testing.T, conv *conveyer.DefaultConveyer, output string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if data, err := conv.RecvContext(ctx, output); err == nil {
+		t.Fatalf("unexpected output on %s: %q", output, data)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	clk := conveyertest.NewFakeClock(time.Unix(0, 0))
+	events := make(chan conveyer.EventKind, 16)
+
+	conv := conveyer.New(4,
+		conveyer.WithClock(clk),
+		conveyer.WithStageEvents(func(event conveyer.StageEvent) {
+			events <- event.Kind
+		}))
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out", conveyer.WithRateLimit(1, 1))
+	runConveyer(t, conv)
+
+	for _, data := range []string{"a", "b"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "decorated: a" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	expectEvent(t, events, conveyer.EventThrottled)
+	expectNothing(t, conv, "out")
+
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "decorated: b" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+}
+
+func TestCircuitBreakerBuffers(t *testing.T) {
+	t.Parallel()
+
+	clk := conveyertest.NewFakeClock(time.Unix(0, 0))
+	events := make(chan conveyer.EventKind, 16)
+
+	conv := conveyer.New(4,
+		conveyer.WithClock(clk),
+		conveyer.WithStageEvents(func(event conveyer.StageEvent) {
+			events <- event.Kind
+		}))
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.SkipPolicy()),
+		conveyer.WithCircuitBreaker(conveyer.BreakerConfig{
+			Failures:   2,
+			Cooldown:   time.Minute,
+			Mode:       conveyer.BreakerBuffer,
+			DeadLetter: "",
+		}))
+	runConveyer(t, conv)
+
+	for _, data := range []string{"no decorator 1", "no decorator 2"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	expectEvent(t, events, conveyer.EventBreakerOpen)
+
+	if err := conv.Send("in", "good"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	expectNothing(t, conv, "out")
+
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+
+	expectEvent(t, events, conveyer.EventBreakerHalfOpen)
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "decorated: good" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	expectEvent(t, events, conveyer.EventBreakerClosed)
+}
+
+func TestCircuitBreakerDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4)
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.SkipPolicy()),
+		conveyer.WithCircuitBreaker(conveyer.BreakerConfig{
+			Failures:   1,
+			Cooldown:   time.Hour,
+			Mode:       conveyer.BreakerDeadLetter,
+			DeadLetter: "rejected",
+		}))
+	runConveyer(t, conv)
+
+	for _, data := range []string{"no decorator", "later"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if got := recvAll(t, conv, "rejected", 1); got[0] != "later" {
+		t.Fatalf("unexpected dead letter: %v", got)
+	}
+
+	expectNothing(t, conv, "out")
+}
+
+func TestCircuitBreakerNeedsSurvivingPerMessageStage(t *testing.T) {
+	t.Parallel()
+
+	breaker := conveyer.WithCircuitBreaker(conveyer.BreakerConfig{
+		Failures:   1,
+		Cooldown:   time.Second,
+		Mode:       conveyer.BreakerBuffer,
+		DeadLetter: "",
+	})
+	skip := conveyer.WithErrorPolicy(conveyer.SkipPolicy())
+
+	conv := conveyer.New(4)
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "a", "b", breaker)
+	conv.RegisterMultiplexerWithOptions(handlers.MultiplexerFunc, []string{"c", "d"}, "e", breaker, skip)
+	conv.RegisterBatch(2, time.Second, joinAll, "f", "g", breaker, skip)
+	conv.RegisterWindow(time.Second, time.Second, joinAll, "h", "i", breaker, skip)
+
+	var validationErr *conveyer.ValidationError
+	if err := conv.Validate(); !errors.As(err, &validationErr) || len(validationErr.Problems) != 4 {
+		t.Fatalf("expected four rejected breakers, got %v", err)
+	}
+
+	for _, problem := range validationErr.Problems {
+		if problem.Kind != conveyer.ProblemUnsupportedOption {
+			t.Fatalf("unexpected problem: %+v", problem)
+		}
+	}
+
+	if stages := conv.Metrics().Stages; len(stages) != 0 {
+		t.Fatalf("rejected stages were registered: %+v", stages)
+	}
+}
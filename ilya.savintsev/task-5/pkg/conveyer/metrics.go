@@ -0,0 +1,226 @@
+package conveyer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var latencyBounds = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64
+	Sum    float64
+	Count  uint64
+}
+
+type StageMetrics struct {
+	Name    string
+	Kind    string
+	In      uint64
+	Out     uint64
+	Errors  uint64
+	Inputs  []string
+	Outputs []string
+	Latency HistogramSnapshot
+}
+
+type ChannelMetrics struct {
+	Name     string
+	Depth    int
+	Capacity int
+}
+
+type Snapshot struct {
+	Stages   []StageMetrics
+	Channels []ChannelMetrics
+}
+
+type MetricsSource interface {
+	Metrics() Snapshot
+}
+
+type stageMetrics struct {
+	in     atomic.Uint64
+	out    atomic.Uint64
+	errors atomic.Uint64
+
+	mu      sync.Mutex
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+func newStageMetrics() *stageMetrics {
+	return &stageMetrics{
+		in:      atomic.Uint64{},
+		out:     atomic.Uint64{},
+		errors:  atomic.Uint64{},
+		mu:      sync.Mutex{},
+		buckets: make([]uint64, len(latencyBounds)),
+		sum:     0,
+		count:   0,
+	}
+}
+
+func (m *stageMetrics) received() {
+	m.in.Add(1)
+}
+
+func (m *stageMetrics) emitted() {
+	m.out.Add(1)
+}
+
+func (m *stageMetrics) observe(elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seconds := elapsed.Seconds()
+
+	for index, bound := range latencyBounds {
+		if seconds <= bound {
+			m.buckets[index]++
+		}
+	}
+
+	m.sum += seconds
+	m.count++
+}
+
+func (m *stageMetrics) histogram() HistogramSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return HistogramSnapshot{
+		Bounds: latencyBounds,
+		Counts: append([]uint64(nil), m.buckets...),
+		Sum:    m.sum,
+		Count:  m.count,
+	}
+}
+
+func (c *Conveyer[T]) Metrics() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Stages:   make([]StageMetrics, 0, len(c.stages)),
+		Channels: make([]ChannelMetrics, 0, len(c.channels)),
+	}
+
+	for _, current := range c.stages {
+		snapshot.Stages = append(snapshot.Stages, StageMetrics{
+			Name:    current.name,
+			Kind:    current.kind,
+			In:      current.metrics.in.Load(),
+			Out:     current.metrics.out.Load(),
+			Errors:  current.metrics.errors.Load(),
+			Inputs:  current.inputs,
+			Outputs: current.outputs,
+			Latency: current.metrics.histogram(),
+		})
+	}
+
+	for _, name := range sortedKeys(c.channels) {
+		channel := c.channels[name].channel
+
+		snapshot.Channels = append(snapshot.Channels, ChannelMetrics{
+			Name:     name,
+			Depth:    len(channel),
+			Capacity: cap(channel),
+		})
+	}
+
+	return snapshot
+}
+
+func (c *Conveyer[T]) Depths(names []string) func(output int) int {
+	return func(output int) int {
+		current, err := c.getPipe(names[output])
+		if err != nil {
+			return 0
+		}
+
+		return len(current.channel)
+	}
+}
+
+func (s Snapshot) WritePrometheus(writer io.Writer) error {
+	var builder strings.Builder
+
+	writeHeader(&builder, "conveyer_stage_messages_in_total", "counter", "Messages read by the stage.")
+
+	for _, stage := range s.Stages {
+		fmt.Fprintf(&builder, "conveyer_stage_messages_in_total{%s} %d\n", stageLabels(stage), stage.In)
+	}
+
+	writeHeader(&builder, "conveyer_stage_messages_out_total", "counter", "Messages written by the stage.")
+
+	for _, stage := range s.Stages {
+		fmt.Fprintf(&builder, "conveyer_stage_messages_out_total{%s} %d\n", stageLabels(stage), stage.Out)
+	}
+
+	writeHeader(&builder, "conveyer_stage_errors_total", "counter", "Handler errors returned by the stage.")
+
+	for _, stage := range s.Stages {
+		fmt.Fprintf(&builder, "conveyer_stage_errors_total{%s} %d\n", stageLabels(stage), stage.Errors)
+	}
+
+	writeHeader(&builder, "conveyer_stage_latency_seconds", "histogram",
+		"Time from handing a message to the handler until its first output.")
+
+	for _, stage := range s.Stages {
+		labels := stageLabels(stage)
+
+		for index, bound := range stage.Latency.Bounds {
+			fmt.Fprintf(&builder, "conveyer_stage_latency_seconds_bucket{%s,le=%q} %d\n",
+				labels, strconv.FormatFloat(bound, 'g', -1, 64), stage.Latency.Counts[index])
+		}
+
+		fmt.Fprintf(&builder, "conveyer_stage_latency_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, stage.Latency.Count)
+		fmt.Fprintf(&builder, "conveyer_stage_latency_seconds_sum{%s} %g\n", labels, stage.Latency.Sum)
+		fmt.Fprintf(&builder, "conveyer_stage_latency_seconds_count{%s} %d\n", labels, stage.Latency.Count)
+	}
+
+	writeHeader(&builder, "conveyer_channel_depth", "gauge", "Messages buffered in the channel.")
+
+	for _, channel := range s.Channels {
+		fmt.Fprintf(&builder, "conveyer_channel_depth{channel=%q} %d\n", channel.Name, channel.Depth)
+	}
+
+	writeHeader(&builder, "conveyer_channel_capacity", "gauge", "Buffer size of the channel.")
+
+	for _, channel := range s.Channels {
+		fmt.Fprintf(&builder, "conveyer_channel_capacity{channel=%q} %d\n", channel.Name, channel.Capacity)
+	}
+
+	if _, err := io.WriteString(writer, builder.String()); err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+
+	return nil
+}
+
+func MetricsHandler(source MetricsSource) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		if err := source.Metrics().WritePrometheus(writer); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func writeHeader(builder *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(builder, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func stageLabels(stage StageMetrics) string {
+	return fmt.Sprintf("stage=%q,kind=%q", stage.Name, stage.Kind)
+}
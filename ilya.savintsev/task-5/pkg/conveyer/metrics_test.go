@@ -0,0 +1,149 @@
+package conveyer_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func runConveyer(t *testing.T, conv *conveyer.DefaultConveyer) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		_ = conv.Run(ctx)
+	}()
+}
+
+func recvAll(t *testing.T, conv *conveyer.DefaultConveyer, output string, count int) []string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result := make([]string, 0, count)
+
+	for range count {
+		data, err := conv.RecvContext(ctx, output)
+		if err != nil {
+			t.Fatalf("unexpected recv error: %v", err)
+		}
+
+		result = append(result, data)
+	}
+
+	return result
+}
+
+func TestMetricsCountMessages(t *testing.T) {
+	t.Parallel()
+
+	const messages = 3
+
+	conv := conveyer.New(messages)
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out", conveyer.WithStageName("prefix"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = conv.Run(ctx)
+	}()
+
+	for range messages {
+		if err := conv.Send("in", "msg"); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	for range messages {
+		if _, err := conv.Recv("out"); err != nil {
+			t.Fatalf("unexpected recv error: %v", err)
+		}
+	}
+
+	snapshot := conv.Metrics()
+	if len(snapshot.Stages) != 1 {
+		t.Fatalf("unexpected stages: %+v", snapshot.Stages)
+	}
+
+	stage := snapshot.Stages[0]
+	if stage.Name != "prefix" || stage.In != messages || stage.Out != messages {
+		t.Fatalf("unexpected stage metrics: %+v", stage)
+	}
+
+	if stage.Latency.Count != messages {
+		t.Fatalf("unexpected latency count: %d", stage.Latency.Count)
+	}
+
+	recorder := httptest.NewRecorder()
+	conveyer.MetricsHandler(conv).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := recorder.Body.String()
+	if !strings.Contains(body, `conveyer_stage_messages_out_total{stage="prefix",kind="decorator"} 3`) {
+		t.Fatalf("unexpected exposition:\n%s", body)
+	}
+
+	if !strings.Contains(body, `conveyer_channel_capacity{channel="in"} 3`) {
+		t.Fatalf("unexpected exposition:\n%s", body)
+	}
+}
+
+func TestLatencyIsMeasuredPerMessage(t *testing.T) {
+	t.Parallel()
+
+	const (
+		hold  = 20 * time.Millisecond
+		bound = 0.05
+	)
+
+	slowFilter := func(ctx context.Context, input chan string, output chan string) error {
+		for data := range input {
+			time.Sleep(hold)
+
+			if data == "drop" {
+				continue
+			}
+
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	messages := []string{"drop", "a", "b"}
+
+	conv := conveyer.New(len(messages))
+	conv.RegisterDecorator(slowFilter, "in", "out")
+	runConveyer(t, conv)
+
+	for _, data := range messages {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	recvAll(t, conv, "out", len(messages)-1)
+
+	latency := conv.Metrics().Stages[0].Latency
+	if latency.Count != uint64(len(messages)-1) {
+		t.Fatalf("unexpected latency count: %+v", latency)
+	}
+
+	for index, le := range latency.Bounds {
+		if le == bound && latency.Counts[index] != latency.Count {
+			t.Fatalf("dropped message skewed latency: %+v", latency)
+		}
+	}
+}
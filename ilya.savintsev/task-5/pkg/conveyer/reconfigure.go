@@ -0,0 +1,220 @@
+package conveyer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrStageNotFound = errors.New("stage not found")
+	ErrStageKind     = errors.New("stage has another kind")
+	ErrChanInUse     = errors.New("chan is used by a stage")
+	ErrChanNotEmpty  = errors.New("chan still holds messages")
+	ErrStageStuck    = errors.New("stage did not stop its handler")
+)
+
+const defaultStageGrace = 5 * time.Second
+
+func WithHotReload() Option {
+	return func(opts *options) {
+		opts.hotReload = true
+	}
+}
+
+func (c *Conveyer[T]) ReplaceDecorator(name string, handlerFunc DecoratorFunc[T]) error {
+	return c.replace(name, kindDecorator, func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+		return handlerFunc(ctx, inputs[0], outputs[0])
+	})
+}
+
+func (c *Conveyer[T]) ReplaceMultiplexer(name string, handlerFunc MultiplexerFunc[T]) error {
+	return c.replace(name, kindMultiplexer, func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+		return handlerFunc(ctx, inputs, outputs[0])
+	})
+}
+
+func (c *Conveyer[T]) ReplaceSeparator(name string, handlerFunc SeparatorFunc[T]) error {
+	return c.replace(name, kindSeparator, func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+		return handlerFunc(ctx, inputs[0], outputs)
+	})
+}
+
+func (c *Conveyer[T]) RemoveStage(name string) error {
+	c.mu.Lock()
+
+	index := c.stageIndex(name)
+	if index < 0 {
+		c.mu.Unlock()
+
+		return fmt.Errorf("%w: %q", ErrStageNotFound, name)
+	}
+
+	removed := c.stages[index]
+	c.stages = append(c.stages[:index], c.stages[index+1:]...)
+	run := c.runs[name]
+
+	if run == nil && !removed.released {
+		for _, output := range removed.outputs {
+			c.channels[output].writers--
+		}
+	}
+	c.mu.Unlock()
+
+	if run != nil {
+		return c.command(run, command[T]{fn: nil, remove: true, done: make(chan struct{}), cancel: make(chan struct{})})
+	}
+
+	return nil
+}
+
+func (c *Conveyer[T]) RemoveChannel(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, exists := c.channels[name]
+	if !exists {
+		return ErrChanNotFound
+	}
+
+	for _, item := range c.stages {
+		if contains(item.inputs, name) || contains(item.outputs, name) || contains(item.deadLetters(), name) {
+			return fmt.Errorf("%w: %q by %q", ErrChanInUse, name, item.name)
+		}
+	}
+
+	if len(current.channel) > 0 {
+		return fmt.Errorf("%w: %q", ErrChanNotEmpty, name)
+	}
+
+	delete(c.channels, name)
+	current.close()
+
+	return nil
+}
+
+func (c *Conveyer[T]) replace(name, kind string, handlerFunc stageFunc[T]) error {
+	c.mu.Lock()
+
+	index := c.stageIndex(name)
+	if index < 0 {
+		c.mu.Unlock()
+
+		return fmt.Errorf("%w: %q", ErrStageNotFound, name)
+	}
+
+	current := &c.stages[index]
+	if current.kind != kind {
+		c.mu.Unlock()
+
+		return fmt.Errorf("%w: %q is a %s", ErrStageKind, name, current.kind)
+	}
+
+	current.fn = handlerFunc
+	run := c.runs[name]
+	c.mu.Unlock()
+
+	if run != nil {
+		return c.command(run, command[T]{
+			fn:     handlerFunc,
+			remove: false,
+			done:   make(chan struct{}),
+			cancel: make(chan struct{}),
+		})
+	}
+
+	return nil
+}
+
+func (c *Conveyer[T]) command(run *stageRun[T], cmd command[T]) error {
+	select {
+	case run.commands <- cmd:
+	case <-run.finished:
+		return nil
+	}
+
+	if c.await(cmd.done) {
+		return nil
+	}
+
+	close(cmd.cancel)
+
+	if c.await(cmd.done) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrStageStuck, run.stage.name)
+}
+
+func (c *Conveyer[T]) await(done <-chan struct{}) bool {
+	grace := c.opts.drainTimeout
+	if grace <= 0 {
+		grace = defaultStageGrace
+	}
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (c *Conveyer[T]) attach(run *stageRun[T]) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	index := c.stageIndex(run.stage.name)
+	if index < 0 {
+		return false
+	}
+
+	run.stage.fn = c.stages[index].fn
+	c.runs[run.stage.name] = run
+
+	return true
+}
+
+func (c *Conveyer[T]) detach(run *stageRun[T], removed bool) {
+	c.mu.Lock()
+
+	if c.runs[run.stage.name] == run {
+		delete(c.runs, run.stage.name)
+
+		if index := c.stageIndex(run.stage.name); index >= 0 {
+			c.stages[index].released = true
+		}
+	}
+
+	drained := make([]*pipe[T], 0, len(run.stage.outputs))
+
+	for _, name := range run.stage.outputs {
+		current := c.channels[name]
+		current.writers--
+
+		if current.writers == 0 && !removed {
+			drained = append(drained, current)
+		}
+	}
+	c.mu.Unlock()
+
+	close(run.finished)
+
+	for _, current := range drained {
+		current.close()
+	}
+}
+
+func (c *Conveyer[T]) stageIndex(name string) int {
+	for index, current := range c.stages {
+		if current.name == name {
+			return index
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,163 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func upperDecorator(ctx context.Context, input chan string, output chan string) error {
+	return handlers.MapDecorator(func(data string) (string, error) {
+		return strings.ToUpper(data), nil
+	})(ctx, input, output)
+}
+
+func TestReplaceDecoratorKeepsMessages(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(8, conveyer.WithHotReload())
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out", conveyer.WithStageName("stage"))
+	runConveyer(t, conv)
+
+	for _, data := range []string{"a", "b"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if got := recvAll(t, conv, "out", 2); got[0] != "decorated: a" || got[1] != "decorated: b" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	if err := conv.ReplaceDecorator("stage", upperDecorator); err != nil {
+		t.Fatalf("unexpected replace error: %v", err)
+	}
+
+	for _, data := range []string{"c", "d"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if got := recvAll(t, conv, "out", 2); got[0] != "C" || got[1] != "D" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+}
+
+func TestRemoveAndAddStageWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(8, conveyer.WithHotReload())
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "out", conveyer.WithStageName("stage"))
+	runConveyer(t, conv)
+
+	if err := conv.RemoveStage("stage"); err != nil {
+		t.Fatalf("unexpected remove error: %v", err)
+	}
+
+	if err := conv.Send("in", "queued"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	conv.RegisterDecoratorWithOptions(upperDecorator, "in", "out", conveyer.WithStageName("upper"))
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "QUEUED" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	if err := conv.RemoveStage("stage"); !errors.Is(err, conveyer.ErrStageNotFound) {
+		t.Fatalf("expected ErrStageNotFound, got %v", err)
+	}
+
+	if err := conv.RemoveChannel("in"); !errors.Is(err, conveyer.ErrChanInUse) {
+		t.Fatalf("expected ErrChanInUse, got %v", err)
+	}
+}
+
+func TestRemoveStageCancelsHandlerIgnoringInput(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+
+	conv := conveyer.New(1, conveyer.WithHotReload(), conveyer.WithDrainTimeout(20*time.Millisecond))
+	conv.RegisterDecoratorWithOptions(func(ctx context.Context, _ chan string, _ chan string) error {
+		close(started)
+		<-ctx.Done()
+
+		return ctx.Err()
+	}, "in", "out", conveyer.WithStageName("stage"))
+	runConveyer(t, conv)
+
+	<-started
+
+	if err := conv.RemoveStage("stage"); err != nil {
+		t.Fatalf("unexpected remove error: %v", err)
+	}
+
+	if stages := conv.Metrics().Stages; len(stages) != 0 {
+		t.Fatalf("removed stage is still reported: %+v", stages)
+	}
+}
+
+func TestRemoveStageReportsStuckHandler(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	started := make(chan struct{})
+
+	conv := conveyer.New(1, conveyer.WithHotReload(), conveyer.WithDrainTimeout(20*time.Millisecond))
+	conv.RegisterDecoratorWithOptions(func(_ context.Context, _ chan string, _ chan string) error {
+		close(started)
+		<-release
+
+		return nil
+	}, "in", "out", conveyer.WithStageName("stage"))
+	runConveyer(t, conv)
+
+	<-started
+
+	if err := conv.RemoveStage("stage"); !errors.Is(err, conveyer.ErrStageStuck) {
+		t.Fatalf("expected ErrStageStuck, got %v", err)
+	}
+}
+
+func TestRunWaitsForHotStages(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1, conveyer.WithHotReload())
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "mid")
+
+	finished := make(chan error, 1)
+
+	go func() {
+		finished <- conv.Run(context.Background())
+	}()
+
+	conv.RegisterDecoratorWithOptions(upperDecorator, "mid", "out", conveyer.WithStageName("late"))
+
+	if err := conv.Send("in", "a"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "DECORATED: A" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := conv.Shutdown(ctx); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	if err := <-finished; err != nil {
+		t.Fatalf("unexpected run error: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package conveyer
+
+import (
+	"context"
+	"fmt"
+)
+
+func WithUndefinedOnClosed() Option {
+	return func(opts *options) {
+		opts.undefinedOnClosed = true
+	}
+}
+
+func (c *Conveyer[T]) Recv(output string) (T, error) {
+	return c.RecvContext(context.Background(), output)
+}
+
+func (c *Conveyer[T]) RecvContext(ctx context.Context, output string) (T, error) {
+	msg, err := c.recv(ctx, output)
+
+	return msg.data, err
+}
+
+func (c *Conveyer[T]) recv(ctx context.Context, output string) (message[T], error) {
+	var zero message[T]
+
+	current, err := c.getPipe(output)
+	if err != nil {
+		return zero, err
+	}
+
+	current.received.Store(true)
+
+	select {
+	case msg, ok := <-current.channel:
+		if ok {
+			if err := current.ack(msg); err != nil {
+				return msg, fmt.Errorf("recv %q: %w", output, err)
+			}
+
+			return msg, nil
+		}
+
+		if c.closedValue != nil {
+			return message[T]{data: *c.closedValue, seq: 0, trace: nil}, nil
+		}
+
+		return zero, ErrChannelClosed
+	case <-ctx.Done():
+		return zero, fmt.Errorf("recv cancelled: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,175 @@
+package conveyer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/clock"
+)
+
+type SendMode int
+
+const (
+	SendBlock SendMode = iota
+	SendBlockContext
+	SendTimeout
+	SendDropNewest
+	SendDropOldest
+)
+
+type SendPolicy struct {
+	Mode    SendMode
+	Timeout time.Duration
+}
+
+type Delivery int
+
+const (
+	Delivered Delivery = iota
+	DeliveredAfterWait
+	DeliveredDroppedOldest
+	DroppedNewest
+	TimedOut
+	Cancelled
+	Rejected
+)
+
+func (d Delivery) String() string {
+	switch d {
+	case Delivered:
+		return "delivered"
+	case DeliveredAfterWait:
+		return "delivered after wait"
+	case DeliveredDroppedOldest:
+		return "delivered, oldest dropped"
+	case DroppedNewest:
+		return "dropped newest"
+	case TimedOut:
+		return "timed out"
+	case Cancelled:
+		return "cancelled"
+	case Rejected:
+		return "rejected"
+	default:
+		return fmt.Sprintf("delivery(%d)", int(d))
+	}
+}
+
+func WithSendPolicy(policy SendPolicy) Option {
+	return func(opts *options) {
+		opts.sendPolicy = policy
+	}
+}
+
+func defaultSendPolicy() SendPolicy {
+	return SendPolicy{
+		Mode:    SendTimeout,
+		Timeout: timeoutTime * time.Millisecond,
+	}
+}
+
+func (c *Conveyer[T]) SendContext(ctx context.Context, input string, data T) error {
+	_, err := c.SendWithPolicy(ctx, input, data, SendPolicy{Mode: SendBlockContext, Timeout: 0})
+
+	return err
+}
+
+func (c *Conveyer[T]) SendWithPolicy(
+	ctx context.Context,
+	input string,
+	data T,
+	policy SendPolicy,
+) (Delivery, error) {
+	current, err := c.getPipe(input)
+	if err != nil {
+		return Rejected, err
+	}
+
+	current.sent.Store(true)
+
+	current.mu.RLock()
+	defer current.mu.RUnlock()
+
+	if current.isClosing() {
+		return Rejected, ErrChannelClosed
+	}
+
+	msg, err := current.wrap(data)
+	if err != nil {
+		return Rejected, err
+	}
+
+	msg.trace = c.newTrace()
+
+	delivery, err := current.deliver(ctx, msg, policy, c.opts.clock)
+	if err != nil {
+		_ = current.ack(msg)
+	}
+
+	return delivery, err
+}
+
+func (p *pipe[T]) deliver(
+	ctx context.Context,
+	msg message[T],
+	policy SendPolicy,
+	clk clock.Clock,
+) (Delivery, error) {
+	select {
+	case p.channel <- msg:
+		return Delivered, nil
+	default:
+	}
+
+	switch policy.Mode {
+	case SendBlock:
+		return p.wait(context.Background(), msg, nil)
+	case SendBlockContext:
+		return p.wait(ctx, msg, nil)
+	case SendTimeout:
+		timer := clk.NewTimer(policy.Timeout)
+		defer timer.Stop()
+
+		return p.wait(ctx, msg, timer.C())
+	case SendDropNewest:
+		return DroppedNewest, ErrFullChannel
+	case SendDropOldest:
+		return p.replaceOldest(msg)
+	default:
+		return Rejected, fmt.Errorf("%w: %d", ErrUnknownSendMode, policy.Mode)
+	}
+}
+
+func (p *pipe[T]) wait(ctx context.Context, msg message[T], timeout <-chan time.Time) (Delivery, error) {
+	select {
+	case p.channel <- msg:
+		return DeliveredAfterWait, nil
+	case <-p.closing:
+		return Rejected, ErrChannelClosed
+	case <-timeout:
+		return TimedOut, ErrTimeout
+	case <-ctx.Done():
+		return Cancelled, fmt.Errorf("send cancelled: %w", ctx.Err())
+	}
+}
+
+func (p *pipe[T]) replaceOldest(msg message[T]) (Delivery, error) {
+	if cap(p.channel) == 0 {
+		return DroppedNewest, ErrFullChannel
+	}
+
+	for {
+		select {
+		case p.channel <- msg:
+			return DeliveredDroppedOldest, nil
+		default:
+		}
+
+		select {
+		case evicted := <-p.channel:
+			_ = p.ack(evicted)
+		default:
+		}
+	}
+}
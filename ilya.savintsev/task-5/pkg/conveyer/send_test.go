@@ -0,0 +1,152 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+)
+
+type sendCase struct {
+	name     string
+	policy   conveyer.SendPolicy
+	full     bool
+	cancel   bool
+	release  bool
+	delivery conveyer.Delivery
+	err      error
+	head     string
+}
+
+func sendCases() []sendCase {
+	const timeout = 20 * time.Millisecond
+
+	return []sendCase{
+		{"block free", conveyer.SendPolicy{Mode: conveyer.SendBlock, Timeout: 0},
+			false, false, false, conveyer.Delivered, nil, "new"},
+		{"block full", conveyer.SendPolicy{Mode: conveyer.SendBlock, Timeout: 0},
+			true, false, true, conveyer.DeliveredAfterWait, nil, "new"},
+		{"block ignores context", conveyer.SendPolicy{Mode: conveyer.SendBlock, Timeout: 0},
+			true, true, true, conveyer.DeliveredAfterWait, nil, "new"},
+		{"context free", conveyer.SendPolicy{Mode: conveyer.SendBlockContext, Timeout: 0},
+			false, false, false, conveyer.Delivered, nil, "new"},
+		{"context full", conveyer.SendPolicy{Mode: conveyer.SendBlockContext, Timeout: 0},
+			true, false, true, conveyer.DeliveredAfterWait, nil, "new"},
+		{"context cancelled", conveyer.SendPolicy{Mode: conveyer.SendBlockContext, Timeout: 0},
+			true, true, false, conveyer.Cancelled, context.Canceled, "old"},
+		{"timeout free", conveyer.SendPolicy{Mode: conveyer.SendTimeout, Timeout: timeout},
+			false, false, false, conveyer.Delivered, nil, "new"},
+		{"timeout full", conveyer.SendPolicy{Mode: conveyer.SendTimeout, Timeout: timeout},
+			true, false, false, conveyer.TimedOut, conveyer.ErrTimeout, "old"},
+		{"timeout released", conveyer.SendPolicy{Mode: conveyer.SendTimeout, Timeout: time.Second},
+			true, false, true, conveyer.DeliveredAfterWait, nil, "new"},
+		{"drop newest free", conveyer.SendPolicy{Mode: conveyer.SendDropNewest, Timeout: 0},
+			false, false, false, conveyer.Delivered, nil, "new"},
+		{"drop newest full", conveyer.SendPolicy{Mode: conveyer.SendDropNewest, Timeout: 0},
+			true, false, false, conveyer.DroppedNewest, conveyer.ErrFullChannel, "old"},
+		{"drop oldest free", conveyer.SendPolicy{Mode: conveyer.SendDropOldest, Timeout: 0},
+			false, false, false, conveyer.Delivered, nil, "new"},
+		{"drop oldest full", conveyer.SendPolicy{Mode: conveyer.SendDropOldest, Timeout: 0},
+			true, false, false, conveyer.DeliveredDroppedOldest, nil, "new"},
+		{"unknown mode", conveyer.SendPolicy{Mode: conveyer.SendMode(42), Timeout: 0},
+			true, false, false, conveyer.Rejected, conveyer.ErrUnknownSendMode, "old"},
+	}
+}
+
+func TestSendPolicies(t *testing.T) {
+	t.Parallel()
+
+	for _, current := range sendCases() {
+		t.Run(current.name, func(t *testing.T) {
+			t.Parallel()
+
+			conv := conveyer.New(1)
+			conv.DeclareInputs("in")
+
+			if current.full {
+				if err := conv.Send("in", "old"); err != nil {
+					t.Fatalf("unexpected fill error: %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if current.cancel {
+				cancel()
+			} else {
+				defer cancel()
+			}
+
+			released := make(chan string, 1)
+
+			if current.release {
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+
+					data, _ := conv.Recv("in")
+					released <- data
+				}()
+			}
+
+			delivery, err := conv.SendWithPolicy(ctx, "in", "new", current.policy)
+			if delivery != current.delivery || !errors.Is(err, current.err) || (current.err == nil && err != nil) {
+				t.Fatalf("got %v %v, want %v %v", delivery, err, current.delivery, current.err)
+			}
+
+			if current.release && <-released != "old" {
+				t.Fatalf("released message is not the oldest one")
+			}
+
+			if data, err := conv.Recv("in"); err != nil || data != current.head {
+				t.Fatalf("got head %q %v, want %q", data, err, current.head)
+			}
+		})
+	}
+}
+
+func TestSendTimeoutWaits(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1)
+	conv.DeclareInputs("in")
+
+	policy := conveyer.SendPolicy{Mode: conveyer.SendTimeout, Timeout: 20 * time.Millisecond}
+
+	if _, err := conv.SendWithPolicy(context.Background(), "in", "first", policy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	started := time.Now()
+
+	delivery, err := conv.SendWithPolicy(context.Background(), "in", "second", policy)
+	if !errors.Is(err, conveyer.ErrTimeout) || delivery != conveyer.TimedOut {
+		t.Fatalf("expected timeout, got %v %v", delivery, err)
+	}
+
+	if time.Since(started) < policy.Timeout {
+		t.Fatalf("timeout fired too early")
+	}
+}
+
+func TestSendToClosedInput(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1)
+	conv.DeclareInputs("in")
+
+	if err := conv.CloseInput("in"); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	for _, mode := range []conveyer.SendMode{
+		conveyer.SendBlock, conveyer.SendBlockContext, conveyer.SendTimeout,
+		conveyer.SendDropNewest, conveyer.SendDropOldest,
+	} {
+		delivery, err := conv.SendWithPolicy(context.Background(), "in", "late",
+			conveyer.SendPolicy{Mode: mode, Timeout: time.Millisecond})
+		if delivery != conveyer.Rejected || !errors.Is(err, conveyer.ErrChannelClosed) {
+			t.Fatalf("mode %d: expected rejection, got %v %v", mode, delivery, err)
+		}
+	}
+}
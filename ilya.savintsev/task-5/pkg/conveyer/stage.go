@@ -0,0 +1,716 @@
+package conveyer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/clock"
+)
+
+type delivery[T any] struct {
+	msg      message[T]
+	source   *pipe[T]
+	input    int
+	handed   time.Time
+	emitted  bool
+	attempts int
+	outputs  []output[T]
+	complete bool
+	settled  bool
+	unsaved  bool
+	rejected bool
+	traced   *trace
+}
+
+type output[T any] struct {
+	index int
+	data  T
+}
+
+type command[T any] struct {
+	fn     stageFunc[T]
+	remove bool
+	done   chan struct{}
+	cancel chan struct{}
+}
+
+type runMode int
+
+const (
+	modeRunning runMode = iota
+	modeReplacing
+	modeRemoving
+	modeStopping
+)
+
+type caseKind int
+
+const (
+	caseFetch caseKind = iota
+	caseHand
+	caseEmit
+	caseDone
+	caseCommand
+	caseCancel
+	caseThrottle
+	caseWake
+	caseRetry
+	caseLetter
+	caseStop
+)
+
+type selectAction[T any] struct {
+	kind   caseKind
+	worker *worker[T]
+	index  int
+}
+
+type stageRun[T any] struct {
+	conv     *Conveyer[T]
+	stage    stage[T]
+	sources  []*pipe[T]
+	targets  []*pipe[T]
+	commands chan command[T]
+	finished chan struct{}
+	tracer   SpanExporter
+	clock    clock.Clock
+	limiter  *rateLimiter
+	breaker  *breaker
+
+	mode      runMode
+	pending   *command[T]
+	fatal     error
+	workers   []*worker[T]
+	ordered   bool
+	reorder   []*delivery[T]
+	queued    [][]*delivery[T]
+	exhausted []bool
+	throttle  []clock.Timer
+	retrying  []retry[T]
+	letters   []letter[T]
+}
+
+func (c *Conveyer[T]) runStage(ctx context.Context, current stage[T]) error {
+	sources, err := c.getPipes(current.inputs)
+	if err != nil {
+		return err
+	}
+
+	targets, err := c.getPipes(current.outputs)
+	if err != nil {
+		return err
+	}
+
+	run := &stageRun[T]{
+		conv:      c,
+		stage:     current,
+		sources:   sources,
+		targets:   targets,
+		commands:  make(chan command[T]),
+		finished:  make(chan struct{}),
+		tracer:    c.opts.tracer,
+		clock:     c.opts.clock,
+		limiter:   newRateLimiter(current.rateLimit, c.opts.clock, c.notifier(current.name)),
+		breaker:   newBreaker(current.breaker, c.opts.clock, c.notifier(current.name)),
+		mode:      modeRunning,
+		pending:   nil,
+		fatal:     nil,
+		workers:   newWorkers[T](current.workerCount(), len(sources)),
+		ordered:   current.ordered && current.workerCount() > 1,
+		reorder:   nil,
+		queued:    make([][]*delivery[T], len(sources)),
+		exhausted: make([]bool, len(sources)),
+		throttle:  make([]clock.Timer, len(sources)),
+		retrying:  nil,
+		letters:   nil,
+	}
+
+	if !c.attach(run) {
+		return nil
+	}
+
+	removed, err := run.serve(ctx)
+	c.detach(run, removed)
+
+	return err
+}
+
+func (r *stageRun[T]) serve(ctx context.Context) (bool, error) {
+	defer r.finish()
+
+	for _, current := range r.workers {
+		r.start(ctx, current)
+	}
+
+	for {
+		decision, wake, timer := gatePass, (<-chan struct{})(nil), clock.Timer(nil)
+		if r.mode == modeRunning && r.gated() {
+			decision, wake, timer = r.admit()
+			if decision == gateDivert {
+				r.divertQueued(ctx)
+
+				continue
+			}
+		}
+
+		handing := r.mode == modeRemoving || (r.mode == modeRunning && decision == gatePass)
+
+		if r.settled() {
+			if timer != nil {
+				timer.Stop()
+			}
+
+			r.release()
+
+			return r.mode == modeRemoving, r.fatal
+		}
+
+		cases, actions := r.cases(ctx, handing, wake, timer)
+		chosen, value, ok := reflect.Select(cases)
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		action := actions[chosen]
+
+		switch action.kind {
+		case caseFetch:
+			r.fetched(action.index, value, ok)
+		case caseHand:
+			r.hand(ctx, action.worker, action.index)
+		case caseEmit:
+			r.emit(ctx, action.worker, action.index, received[T](value))
+		case caseDone:
+			err, _ := value.Interface().(error)
+
+			r.handlerDone(ctx, action.worker, err)
+		case caseCommand:
+			r.commanded(received[command[T]](value))
+		case caseCancel:
+			r.cancelWorkers()
+		case caseThrottle:
+			r.throttle[action.index] = nil
+		case caseWake:
+		case caseRetry:
+			r.retried(ctx, action.index)
+		case caseLetter:
+			r.posted(ctx)
+		case caseStop:
+			r.stop()
+		}
+	}
+}
+
+func (r *stageRun[T]) cases(
+	ctx context.Context,
+	handing bool,
+	wake <-chan struct{},
+	timer clock.Timer,
+) ([]reflect.SelectCase, []selectAction[T]) {
+	size := len(r.sources)*(len(r.workers)+1) + len(r.workers)*(len(r.targets)+1) + len(r.retrying) + 5
+	cases := make([]reflect.SelectCase, 0, size)
+	actions := make([]selectAction[T], 0, size)
+
+	add := func(dir reflect.SelectDir, channel, send reflect.Value, action selectAction[T]) {
+		cases = append(cases, reflect.SelectCase{Dir: dir, Chan: channel, Send: send})
+		actions = append(actions, action)
+	}
+
+	recv := func(channel any, kind caseKind, current *worker[T], index int) {
+		add(reflect.SelectRecv, reflect.ValueOf(channel), reflect.Value{},
+			selectAction[T]{kind: kind, worker: current, index: index})
+	}
+
+	for index := range r.sources {
+		switch {
+		case len(r.queued[index]) == 0:
+			if r.mode == modeRunning && !r.exhausted[index] && len(r.letters) == 0 {
+				recv(r.sources[index].channel, caseFetch, nil, index)
+			}
+		case r.mode == modeRunning && r.throttle[index] != nil:
+			recv(r.throttle[index].C(), caseThrottle, nil, index)
+		case handing && !r.awaitingRetry(index):
+			data := r.queued[index][0].msg.data
+
+			for _, current := range r.workers {
+				if current.running && !current.closed[index] && r.accepts(current, index) {
+					add(reflect.SelectSend, reflect.ValueOf(current.inputs[index]), reflect.ValueOf(&data).Elem(),
+						selectAction[T]{kind: caseHand, worker: current, index: index})
+				}
+			}
+		}
+	}
+
+	for _, current := range r.workers {
+		if !current.running {
+			continue
+		}
+
+		for index := range current.outputs {
+			recv(current.outputs[index], caseEmit, current, index)
+		}
+
+		recv(current.done, caseDone, current, 0)
+	}
+
+	for index, pending := range r.retrying {
+		recv(pending.timer.C(), caseRetry, nil, index)
+	}
+
+	if len(r.letters) > 0 {
+		add(reflect.SelectSend, reflect.ValueOf(r.letters[0].target.channel), reflect.ValueOf(r.letters[0].msg),
+			selectAction[T]{kind: caseLetter, worker: nil, index: 0})
+	}
+
+	if r.mode == modeRunning {
+		recv(r.commands, caseCommand, nil, 0)
+	}
+
+	if r.pending != nil && r.pending.cancel != nil {
+		recv(r.pending.cancel, caseCancel, nil, 0)
+	}
+
+	if timer != nil {
+		recv(timer.C(), caseWake, nil, 0)
+	}
+
+	if wake != nil {
+		recv(wake, caseWake, nil, 0)
+	}
+
+	if r.mode != modeStopping {
+		recv(ctx.Done(), caseStop, nil, 0)
+	}
+
+	return cases, actions
+}
+
+func (r *stageRun[T]) finish() {
+	for _, current := range r.workers {
+		if current.cancel != nil {
+			current.cancel()
+		}
+	}
+
+	for _, timer := range r.throttle {
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+
+	for _, waiting := range r.reorder {
+		if !waiting.settled {
+			r.conv.dropped(r.stage.name, waiting.msg.data)
+		}
+	}
+
+	if r.pending != nil {
+		close(r.pending.done)
+		r.pending = nil
+	}
+}
+
+func (r *stageRun[T]) settled() bool {
+	if len(r.retrying) > 0 || len(r.letters) > 0 {
+		return false
+	}
+
+	for _, current := range r.workers {
+		if current.running {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *stageRun[T]) fetched(index int, value reflect.Value, ok bool) {
+	if !ok {
+		r.exhausted[index] = true
+		r.closeInput(index)
+
+		return
+	}
+
+	msg := received[message[T]](value)
+
+	r.stage.metrics.received()
+
+	if r.limiter != nil {
+		if delay := r.limiter.reserve(); delay > 0 {
+			r.throttle[index] = r.clock.NewTimer(delay)
+		}
+	}
+
+	r.queued[index] = append(r.queued[index], &delivery[T]{
+		msg:      msg,
+		source:   r.sources[index],
+		input:    index,
+		handed:   time.Time{},
+		emitted:  false,
+		attempts: 0,
+		outputs:  nil,
+		complete: false,
+		settled:  false,
+		unsaved:  false,
+		rejected: false,
+		traced:   nil,
+	})
+}
+
+func (r *stageRun[T]) hand(ctx context.Context, current *worker[T], index int) {
+	handed := r.queued[index][0]
+	r.queued[index] = r.queued[index][1:]
+	r.handed(ctx, current, handed)
+
+	if len(r.queued[index]) > 0 {
+		return
+	}
+
+	if r.exhausted[index] {
+		r.closeInput(index)
+	}
+
+	if r.mode == modeRemoving && !r.holding() {
+		r.closeInputs()
+	}
+}
+
+func (r *stageRun[T]) emit(ctx context.Context, current *worker[T], index int, data T) {
+	from, consumed := r.attribute(current, data)
+
+	for _, handed := range consumed {
+		if !handed.emitted && (handed == from || aggregating(r.stage.kind)) {
+			handed.emitted = true
+			r.stage.metrics.observe(time.Since(handed.handed))
+		}
+	}
+
+	if r.ordered && from != nil && !from.settled {
+		from.outputs = append(from.outputs, output[T]{index: index, data: data})
+		r.completed(ctx, consumed)
+
+		return
+	}
+
+	if err := r.put(ctx, index, data, from); err != nil {
+		for _, handed := range append(consumed, current.held()...) {
+			handed.unsaved = true
+		}
+	}
+
+	if r.stage.kind != kindSeparator {
+		for _, handed := range consumed {
+			r.settle(handed)
+		}
+	}
+}
+
+func (r *stageRun[T]) put(ctx context.Context, index int, data T, from *delivery[T]) error {
+	r.stage.metrics.emitted()
+
+	target := r.targets[index]
+
+	msg, err := target.wrap(data)
+	if err != nil {
+		r.stage.metrics.errors.Add(1)
+	}
+
+	msg.trace = r.span(from)
+
+	select {
+	case target.channel <- msg:
+	case <-ctx.Done():
+		if !target.offer(msg) {
+			r.conv.dropped(r.stage.name, msg.data)
+		}
+	}
+
+	return err
+}
+
+func (r *stageRun[T]) settle(handed *delivery[T]) {
+	if handed.settled {
+		return
+	}
+
+	handed.settled = true
+
+	if r.breaker != nil && !handed.rejected {
+		r.breaker.succeeded()
+	}
+
+	if handed.unsaved {
+		return
+	}
+
+	if err := handed.source.ack(handed.msg); err != nil {
+		r.stage.metrics.errors.Add(1)
+	}
+}
+
+func (r *stageRun[T]) handlerDone(ctx context.Context, current *worker[T], err error) {
+	current.running = false
+	current.cancel()
+
+	culprit := current.origin()
+	inflight := current.take()
+
+	switch {
+	case r.mode == modeStopping || ctx.Err() != nil:
+		if err != nil && r.fatal == nil {
+			r.fatal = err
+		}
+
+		if r.mode != modeStopping {
+			r.stop()
+		}
+
+		return
+	case r.mode == modeReplacing || r.mode == modeRemoving:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			r.stage.metrics.errors.Add(1)
+		}
+
+		if err != nil {
+			for _, handed := range inflight {
+				handed.unsaved = true
+				handed.rejected = true
+			}
+		}
+
+		r.completed(ctx, inflight)
+		r.advance(ctx)
+
+		return
+	case err == nil:
+		r.completed(ctx, inflight)
+
+		return
+	}
+
+	if err := r.failed(ctx, culprit, err); err != nil {
+		r.fatal = err
+		r.stop()
+
+		return
+	}
+
+	r.start(ctx, current)
+}
+
+func (r *stageRun[T]) failed(ctx context.Context, failed *delivery[T], cause error) error {
+	r.stage.metrics.errors.Add(1)
+
+	if r.breaker != nil {
+		r.breaker.failed()
+	}
+
+	if failed == nil {
+		return cause
+	}
+
+	failed.outputs = nil
+	failed.complete = true
+	failed.rejected = true
+
+	if err := r.conv.handleFailure(r, failed, cause); err != nil {
+		return err
+	}
+
+	if !failed.complete {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		failed.unsaved = true
+	}
+
+	if r.ordered {
+		r.completed(ctx, nil)
+	} else {
+		r.settle(failed)
+	}
+
+	return nil
+}
+
+func (r *stageRun[T]) commanded(cmd command[T]) {
+	r.pending = &cmd
+
+	if !cmd.remove {
+		r.mode = modeReplacing
+		r.closeInputs()
+
+		return
+	}
+
+	r.mode = modeRemoving
+
+	if !r.holding() {
+		r.closeInputs()
+	}
+}
+
+func (r *stageRun[T]) advance(ctx context.Context) {
+	if r.mode == modeReplacing && r.settled() {
+		r.replaced(ctx)
+	}
+}
+
+func (r *stageRun[T]) replaced(ctx context.Context) {
+	r.stage.fn = r.pending.fn
+	r.mode = modeRunning
+	close(r.pending.done)
+	r.pending = nil
+
+	for _, current := range r.workers {
+		r.start(ctx, current)
+	}
+}
+
+func (r *stageRun[T]) cancelWorkers() {
+	r.pending.cancel = nil
+
+	for _, current := range r.workers {
+		if current.running {
+			current.cancel()
+		}
+	}
+}
+
+func (r *stageRun[T]) stop() {
+	r.mode = modeStopping
+	r.release()
+	r.closeInputs()
+
+	for _, current := range r.workers {
+		if current.running {
+			current.cancel()
+		}
+	}
+}
+
+func (r *stageRun[T]) release() {
+	for index, queue := range r.queued {
+		for _, current := range queue {
+			if current.attempts > 0 && r.ordered {
+				continue
+			}
+
+			if !current.source.offer(current.msg) {
+				r.conv.dropped(r.stage.name, current.msg.data)
+			}
+		}
+
+		r.queued[index] = nil
+	}
+
+	r.abandon()
+}
+
+func (r *stageRun[T]) requeue(failed *delivery[T]) {
+	failed.complete = false
+	failed.settled = false
+	failed.traced = nil
+	failed.rejected = false
+	r.queued[failed.input] = append([]*delivery[T]{failed}, r.queued[failed.input]...)
+}
+
+func (r *stageRun[T]) closeInput(index int) {
+	for _, current := range r.workers {
+		if current.running && !current.closed[index] {
+			current.closed[index] = true
+			close(current.inputs[index])
+		}
+	}
+}
+
+func (r *stageRun[T]) closeInputs() {
+	for index := range r.sources {
+		r.closeInput(index)
+	}
+}
+
+func (r *stageRun[T]) holding() bool {
+	for _, queue := range r.queued {
+		if len(queue) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *stageRun[T]) gated() bool {
+	for index, queue := range r.queued {
+		if len(queue) > 0 && r.throttle[index] == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *stageRun[T]) admit() (gateDecision, <-chan struct{}, clock.Timer) {
+	if r.breaker == nil {
+		return gatePass, nil, nil
+	}
+
+	decision, changed, after := r.breaker.allow()
+	if decision != gateWait {
+		return decision, nil, nil
+	}
+
+	return gateWait, changed, r.clock.NewTimer(after)
+}
+
+func (r *stageRun[T]) divertQueued(ctx context.Context) {
+	for index, queue := range r.queued {
+		if len(queue) == 0 || r.throttle[index] != nil {
+			continue
+		}
+
+		diverted := queue[0]
+		r.queued[index] = queue[1:]
+		diverted.rejected = true
+
+		if err := r.post(r.stage.breaker.DeadLetter, diverted); err != nil {
+			r.stage.metrics.errors.Add(1)
+			r.conv.failures.add(Failure[T]{
+				Stage:   r.stage.name,
+				Payload: diverted.msg.data,
+				Err:     err,
+				Time:    r.clock.Now(),
+			})
+
+			r.settle(diverted)
+
+			if diverted.attempts > 0 {
+				diverted.complete = true
+				r.completed(ctx, nil)
+			}
+		}
+
+		if len(r.queued[index]) == 0 && r.exhausted[index] {
+			r.closeInput(index)
+		}
+	}
+}
+
+func perMessage(kind string) bool {
+	return kind != kindMultiplexer && !aggregating(kind)
+}
+
+func aggregating(kind string) bool {
+	return kind == kindBatch || kind == kindWindow
+}
+
+func received[V any](value reflect.Value) V {
+	current, _ := value.Interface().(V)
+
+	return current
+}
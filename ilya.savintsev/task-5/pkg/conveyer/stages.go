@@ -0,0 +1,72 @@
+package conveyer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func (c *Conveyer[T]) RegisterFilter(keep func(T) bool, input, output string, opts ...StageOption) {
+	c.registerOneToOne(kindFilter, handlers.FilterDecorator(keep), input, output, opts)
+}
+
+func (c *Conveyer[T]) RegisterMap(
+	transform func(T) (T, error),
+	input, output string,
+	opts ...StageOption,
+) {
+	c.registerOneToOne(kindMap, handlers.MapDecorator(transform), input, output, opts)
+}
+
+func (c *Conveyer[T]) RegisterBatch(
+	size int,
+	interval time.Duration,
+	merge func([]T) T,
+	input, output string,
+	opts ...StageOption,
+) {
+	if size < 1 {
+		c.reject(Problem{Kind: ProblemInvalidParameter, Channel: input, Detail: fmt.Sprintf("batch size %d", size)})
+
+		return
+	}
+
+	handlerFunc := handlers.BatchDecoratorWithClock(c.opts.clock, size, interval, merge)
+
+	c.registerOneToOne(kindBatch, handlerFunc, input, output, opts)
+}
+
+func (c *Conveyer[T]) RegisterWindow(
+	size, slide time.Duration,
+	aggregate func([]T) T,
+	input, output string,
+	opts ...StageOption,
+) {
+	if size <= 0 || slide <= 0 {
+		c.reject(Problem{
+			Kind:    ProblemInvalidParameter,
+			Channel: input,
+			Detail:  fmt.Sprintf("window size %s, slide %s", size, slide),
+		})
+
+		return
+	}
+
+	handlerFunc := handlers.WindowDecoratorWithClock(c.opts.clock, size, slide, aggregate)
+
+	c.registerOneToOne(kindWindow, handlerFunc, input, output, opts)
+}
+
+func (c *Conveyer[T]) registerOneToOne(
+	kind string,
+	handlerFunc DecoratorFunc[T],
+	input, output string,
+	opts []StageOption,
+) {
+	c.addStage(kind, []string{input}, []string{output},
+		func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+			return handlerFunc(ctx, inputs[0], outputs[0])
+		}, opts)
+}
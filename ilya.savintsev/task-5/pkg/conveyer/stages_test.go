@@ -0,0 +1,142 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/conveyertest"
+)
+
+var errEmpty = errors.New("empty")
+
+type stampClock struct {
+	*conveyertest.FakeClock
+
+	stamps chan time.Time
+}
+
+func (c stampClock) Now() time.Time {
+	now := c.FakeClock.Now()
+	c.stamps <- now
+
+	return now
+}
+
+func joinAll(items []string) string {
+	return strings.Join(items, ",")
+}
+
+func TestRegisterFilterAndMap(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4)
+	conv.RegisterFilter(func(data string) bool { return !strings.HasPrefix(data, "skip") }, "in", "kept")
+	conv.RegisterMap(func(data string) (string, error) {
+		if data == "" {
+			return "", errEmpty
+		}
+
+		return strings.ToUpper(data), nil
+	}, "kept", "out")
+	runConveyer(t, conv)
+
+	for _, data := range []string{"a", "skip me", "b"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if got := recvAll(t, conv, "out", 2); got[0] != "A" || got[1] != "B" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	stages := conv.Metrics().Stages
+	if stages[0].Kind != "filter" || stages[0].In != 3 || stages[0].Out != 2 || stages[1].Kind != "map" {
+		t.Fatalf("unexpected stage metrics: %+v", stages)
+	}
+}
+
+func TestRegisterBatch(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(8)
+	conv.RegisterBatch(2, time.Hour, joinAll, "in", "out")
+	runConveyer(t, conv)
+
+	for _, data := range []string{"a", "b", "c", "d"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if got := recvAll(t, conv, "out", 2); got[0] != "a,b" || got[1] != "c,d" {
+		t.Fatalf("unexpected batches: %v", got)
+	}
+}
+
+func TestRegisterWindow(t *testing.T) {
+	t.Parallel()
+
+	clk := stampClock{FakeClock: conveyertest.NewFakeClock(time.Unix(0, 0)), stamps: make(chan time.Time, 4)}
+
+	conv := conveyer.New(4, conveyer.WithClock(clk))
+	conv.RegisterWindow(2*time.Second, time.Second, joinAll, "in", "out")
+	runConveyer(t, conv)
+
+	clk.BlockUntil(1)
+
+	push := func(data string) {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+
+		<-clk.stamps
+	}
+
+	push("a")
+	clk.Advance(time.Second)
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "a" {
+		t.Fatalf("unexpected window: %v", got)
+	}
+
+	push("b")
+	clk.Advance(time.Second)
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "b" {
+		t.Fatalf("unexpected window: %v", got)
+	}
+}
+
+func TestBatchAndWindowRejectBadSizesAtRegistration(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4)
+	conv.RegisterBatch(0, time.Second, joinAll, "a", "b")
+	conv.RegisterWindow(time.Second, 0, joinAll, "c", "d")
+	conv.RegisterWindow(-time.Second, time.Second, joinAll, "e", "f")
+
+	var validationErr *conveyer.ValidationError
+	if err := conv.Validate(); !errors.As(err, &validationErr) || len(validationErr.Problems) != 3 {
+		t.Fatalf("expected three rejected stages, got %v", err)
+	}
+
+	for index, channel := range []string{"a", "c", "e"} {
+		problem := validationErr.Problems[index]
+		if problem.Kind != conveyer.ProblemInvalidParameter || problem.Channel != channel {
+			t.Fatalf("unexpected problem %d: %+v", index, problem)
+		}
+	}
+
+	if stages := conv.Metrics().Stages; len(stages) != 0 {
+		t.Fatalf("rejected stages were registered: %+v", stages)
+	}
+
+	if err := conv.Run(context.Background()); !errors.Is(err, conveyer.ErrInvalidGraph) {
+		t.Fatalf("expected run to refuse the stages, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package conveyer
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+type Span struct {
+	TraceID  string
+	SpanID   string
+	ParentID string
+	Stage    string
+	Kind     string
+	Start    time.Time
+	End      time.Time
+}
+
+type Envelope[T any] struct {
+	ID      string
+	Created time.Time
+	Data    T
+	Spans   []Span
+}
+
+type SpanExporter interface {
+	ExportSpan(span Span)
+}
+
+type trace struct {
+	id      string
+	created time.Time
+	spans   []Span
+}
+
+func WithTracing(exporter SpanExporter) Option {
+	return func(opts *options) {
+		opts.tracer = exporter
+	}
+}
+
+func (c *Conveyer[T]) RecvEnvelope(ctx context.Context, output string) (Envelope[T], error) {
+	msg, err := c.recv(ctx, output)
+	if err != nil {
+		return Envelope[T]{ID: "", Created: time.Time{}, Data: msg.data, Spans: nil}, err
+	}
+
+	if msg.trace == nil {
+		return Envelope[T]{ID: "", Created: time.Time{}, Data: msg.data, Spans: nil}, nil
+	}
+
+	return Envelope[T]{
+		ID:      msg.trace.id,
+		Created: msg.trace.created,
+		Data:    msg.data,
+		Spans:   append([]Span(nil), msg.trace.spans...),
+	}, nil
+}
+
+func (c *Conveyer[T]) newTrace() *trace {
+	if c.opts.tracer == nil {
+		return nil
+	}
+
+	return &trace{id: newID(), created: time.Now(), spans: nil}
+}
+
+func (r *stageRun[T]) span(from *delivery[T]) *trace {
+	if from == nil {
+		return r.conv.newTrace()
+	}
+
+	if r.tracer == nil || from.msg.trace == nil {
+		return nil
+	}
+
+	if from.traced != nil {
+		return from.traced
+	}
+
+	parent := ""
+	if len(from.msg.trace.spans) > 0 {
+		parent = from.msg.trace.spans[len(from.msg.trace.spans)-1].SpanID
+	}
+
+	span := Span{
+		TraceID:  from.msg.trace.id,
+		SpanID:   newID()[:16],
+		ParentID: parent,
+		Stage:    r.stage.name,
+		Kind:     r.stage.kind,
+		Start:    from.handed,
+		End:      time.Now(),
+	}
+
+	r.tracer.ExportSpan(span)
+
+	spans := make([]Span, 0, len(from.msg.trace.spans)+1)
+	spans = append(spans, from.msg.trace.spans...)
+	from.traced = &trace{id: from.msg.trace.id, created: from.msg.trace.created, spans: append(spans, span)}
+
+	return from.traced
+}
+
+func newID() string {
+	return fmt.Sprintf("%016x%016x", rand.Uint64(), rand.Uint64())
+}
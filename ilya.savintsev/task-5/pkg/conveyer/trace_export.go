@@ -0,0 +1,153 @@
+package conveyer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const (
+	spanKindInternal = 1
+	traceFilePerm    = 0o644
+)
+
+var ErrTraceExport = errors.New("span export failure")
+
+type MemoryCollector struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+func NewMemoryCollector() *MemoryCollector {
+	return &MemoryCollector{mu: sync.Mutex{}, spans: []Span{}}
+}
+
+func (m *MemoryCollector) ExportSpan(span Span) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.spans = append(m.spans, span)
+}
+
+func (m *MemoryCollector) Spans() []Span {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Span(nil), m.spans...)
+}
+
+type FileExporter struct {
+	mu      sync.Mutex
+	file    *os.File
+	service string
+	err     error
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpExport struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func NewFileExporter(path, service string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, traceFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTraceExport, err)
+	}
+
+	return &FileExporter{mu: sync.Mutex{}, file: file, service: service, err: nil}, nil
+}
+
+func (f *FileExporter) ExportSpan(span Span) {
+	line, err := json.Marshal(otlpExport{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{attribute("service.name", f.service)}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "conveyer"},
+			Spans: []otlpSpan{{
+				TraceID:           span.TraceID,
+				SpanID:            span.SpanID,
+				ParentSpanID:      span.ParentID,
+				Name:              span.Stage,
+				Kind:              spanKindInternal,
+				StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+				EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+				Attributes:        []otlpAttribute{attribute("conveyer.stage.kind", span.Kind)},
+			}},
+		}},
+	}}})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil || f.file == nil {
+		return
+	}
+
+	if err == nil {
+		_, err = f.file.Write(append(line, '\n'))
+	}
+
+	if err != nil {
+		f.err = fmt.Errorf("%w: %w", ErrTraceExport, err)
+	}
+}
+
+func (f *FileExporter) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return f.err
+	}
+
+	if err := f.file.Close(); err != nil && f.err == nil {
+		f.err = fmt.Errorf("%w: %w", ErrTraceExport, err)
+	}
+
+	f.file = nil
+
+	return f.err
+}
+
+func attribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+}
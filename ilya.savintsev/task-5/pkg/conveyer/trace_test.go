@@ -0,0 +1,297 @@
+package conveyer_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func TestTracingThroughStages(t *testing.T) {
+	t.Parallel()
+
+	collector := conveyer.NewMemoryCollector()
+
+	conv := conveyer.New(4, conveyer.WithTracing(collector))
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "in", "a", conveyer.WithStageName("first"))
+	conv.RegisterDecoratorWithOptions(handlers.PrefixDecoratorFunc, "a", "b", conveyer.WithStageName("second"))
+	conv.RegisterSeparatorWithOptions(handlers.SeparatorFunc, "b", []string{"x", "y"}, conveyer.WithStageName("split"))
+	runConveyer(t, conv)
+
+	if err := conv.Send("in", "msg"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	envelope, err := conv.RecvEnvelope(ctx, "x")
+	if err != nil {
+		t.Fatalf("unexpected recv error: %v", err)
+	}
+
+	if envelope.Data != "decorated: msg" || envelope.ID == "" || envelope.Created.IsZero() {
+		t.Fatalf("unexpected envelope: %+v", envelope)
+	}
+
+	if len(envelope.Spans) != 3 {
+		t.Fatalf("unexpected spans: %+v", envelope.Spans)
+	}
+
+	for index, name := range []string{"first", "second", "split"} {
+		span := envelope.Spans[index]
+		if span.Stage != name || span.TraceID != envelope.ID || span.End.Before(span.Start) {
+			t.Fatalf("unexpected span %d: %+v", index, span)
+		}
+
+		if index > 0 && span.ParentID != envelope.Spans[index-1].SpanID {
+			t.Fatalf("span %d is not linked to its parent: %+v", index, span)
+		}
+	}
+
+	if exported := collector.Spans(); len(exported) != 3 {
+		t.Fatalf("unexpected exported spans: %+v", exported)
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	runConveyer(t, conv)
+
+	if err := conv.Send("in", "msg"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	envelope, err := conv.RecvEnvelope(context.Background(), "out")
+	if err != nil {
+		t.Fatalf("unexpected recv error: %v", err)
+	}
+
+	if envelope.ID != "" || len(envelope.Spans) != 0 {
+		t.Fatalf("unexpected envelope: %+v", envelope)
+	}
+}
+
+func TestFileExporterWritesOTLP(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+
+	exporter, err := conveyer.NewFileExporter(path, "test")
+	if err != nil {
+		t.Fatalf("unexpected exporter error: %v", err)
+	}
+
+	start := time.Unix(1, 0)
+	exporter.ExportSpan(conveyer.Span{
+		TraceID:  "0123456789abcdef0123456789abcdef",
+		SpanID:   "0123456789abcdef",
+		ParentID: "",
+		Stage:    "stage",
+		Kind:     "decorator",
+		Start:    start,
+		End:      start.Add(time.Second),
+	})
+
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		t.Fatalf("no spans written")
+	}
+
+	var export struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []map[string]any `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+
+	if err := json.Unmarshal(scanner.Bytes(), &export); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	span := export.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if span["name"] != "stage" || span["startTimeUnixNano"] != "1000000000" || span["endTimeUnixNano"] != "2000000000" {
+		t.Fatalf("unexpected span: %v", span)
+	}
+}
+
+func TestTracingFollowsEachMessage(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4, conveyer.WithTracing(conveyer.NewMemoryCollector()))
+	conv.RegisterDecorator(func(ctx context.Context, input chan string, output chan string) error {
+		for data := range input {
+			if data == "drop" {
+				continue
+			}
+
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	}, "in", "out")
+	runConveyer(t, conv)
+
+	if err := conv.Send("in", "drop"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	sent := time.Now()
+
+	if err := conv.Send("in", "keep"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	envelope, err := conv.RecvEnvelope(ctx, "out")
+	if err != nil {
+		t.Fatalf("unexpected recv error: %v", err)
+	}
+
+	if envelope.Data != "keep" || envelope.Created.Before(sent) || len(envelope.Spans) != 1 {
+		t.Fatalf("output carries another message's trace: %+v", envelope)
+	}
+}
+
+func TestTracingBroadcastSeparator(t *testing.T) {
+	t.Parallel()
+
+	collector := conveyer.NewMemoryCollector()
+
+	conv := conveyer.New(4, conveyer.WithTracing(collector))
+	conv.RegisterSeparator(handlers.BroadcastSeparator[string](), "in", []string{"a", "b"})
+	runConveyer(t, conv)
+
+	if err := conv.Send("in", "msg"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	first, err := conv.RecvEnvelope(ctx, "a")
+	if err != nil {
+		t.Fatalf("unexpected recv error: %v", err)
+	}
+
+	second, err := conv.RecvEnvelope(ctx, "b")
+	if err != nil {
+		t.Fatalf("unexpected recv error: %v", err)
+	}
+
+	if first.ID == "" || second.ID != first.ID || len(second.Spans) != 1 || second.Spans[0] != first.Spans[0] {
+		t.Fatalf("broadcast envelopes differ: %+v %+v", first, second)
+	}
+
+	if exported := collector.Spans(); len(exported) != 1 {
+		t.Fatalf("unexpected exported spans: %+v", exported)
+	}
+}
+
+func pairMultiplexer(ctx context.Context, inputs []chan string, output chan string) error {
+	for {
+		first, ok := <-inputs[0]
+		if !ok {
+			return nil
+		}
+
+		second, ok := <-inputs[1]
+		if !ok {
+			return nil
+		}
+
+		for _, data := range []string{first, second} {
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func TestTracingMultiplexerNeverBorrowsTraces(t *testing.T) {
+	t.Parallel()
+
+	const messages = 200
+
+	cases := map[string]func(context.Context, []chan string, chan string) error{
+		"sequential": handlers.PriorityMultiplexer[string](),
+		"holding":    pairMultiplexer,
+	}
+
+	for name, handler := range cases {
+		conv := conveyer.New(messages, conveyer.WithTracing(conveyer.NewMemoryCollector()))
+		conv.RegisterMultiplexer(handler, []string{"a", "b"}, "out")
+
+		windows := make(map[string][2]time.Time, messages)
+
+		for index := range messages {
+			input := []string{"a", "b"}[index%2]
+			data := input + strconv.Itoa(index)
+			before := time.Now()
+
+			if err := conv.Send(input, data); err != nil {
+				t.Fatalf("unexpected send error: %v", err)
+			}
+
+			windows[data] = [2]time.Time{before, time.Now()}
+		}
+
+		runConveyer(t, conv)
+
+		for _, envelope := range recvEnvelopes(t, conv, "out", messages) {
+			window := windows[envelope.Data]
+			if len(envelope.Spans) == 0 || envelope.Created.Before(window[0]) || envelope.Created.After(window[1]) {
+				t.Fatalf("%s: %q does not carry its own trace: %+v", name, envelope.Data, envelope)
+			}
+		}
+	}
+}
+
+func recvEnvelopes(t *testing.T, conv *conveyer.DefaultConveyer, output string, count int) []conveyer.Envelope[string] {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	envelopes := make([]conveyer.Envelope[string], 0, count)
+
+	for range count {
+		envelope, err := conv.RecvEnvelope(ctx, output)
+		if err != nil {
+			t.Fatalf("unexpected recv error: %v", err)
+		}
+
+		envelopes = append(envelopes, envelope)
+	}
+
+	return envelopes
+}
@@ -0,0 +1,313 @@
+package conveyer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidGraph = errors.New("invalid conveyer graph")
+
+type ProblemKind string
+
+const (
+	ProblemNoProducer        ProblemKind = "no producer"
+	ProblemNoConsumer        ProblemKind = "no consumer"
+	ProblemCycle             ProblemKind = "cycle"
+	ProblemRegisteredLate    ProblemKind = "registered after run"
+	ProblemDuplicateWriters  ProblemKind = "duplicate writers"
+	ProblemDuplicateStage    ProblemKind = "duplicate stage name"
+	ProblemMessagePolicy     ProblemKind = "per-message policy"
+	ProblemUnsupportedOption ProblemKind = "unsupported option"
+	ProblemInvalidParameter  ProblemKind = "invalid parameter"
+)
+
+type Problem struct {
+	Kind    ProblemKind
+	Channel string
+	Detail  string
+}
+
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+
+	for _, problem := range e.Problems {
+		lines = append(lines, fmt.Sprintf("%s: %q %s", problem.Kind, problem.Channel, problem.Detail))
+	}
+
+	return fmt.Sprintf("%s: %s", ErrInvalidGraph, strings.Join(lines, "; "))
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidGraph
+}
+
+func (c *Conveyer[T]) DeclareInputs(names ...string) {
+	for _, name := range names {
+		c.obtainChannel(name)
+	}
+
+	c.mu.Lock()
+	c.inputs = append(c.inputs, names...)
+	c.mu.Unlock()
+}
+
+func (c *Conveyer[T]) DeclareOutputs(names ...string) {
+	for _, name := range names {
+		c.obtainChannel(name)
+	}
+
+	c.mu.Lock()
+	c.outputs = append(c.outputs, names...)
+	c.mu.Unlock()
+}
+
+func (c *Conveyer[T]) Validate() error {
+	problems, undeclared := c.check()
+
+	return validationError(append(problems, undeclared...))
+}
+
+func (c *Conveyer[T]) validateForRun() error {
+	problems, undeclared := c.check()
+
+	c.mu.RLock()
+	strictInputs, strictOutputs := len(c.inputs) > 0, len(c.outputs) > 0
+	c.mu.RUnlock()
+
+	var inputs, outputs []string
+
+	for _, problem := range undeclared {
+		switch {
+		case problem.Kind == ProblemNoProducer && !strictInputs:
+			inputs = append(inputs, problem.Channel)
+		case problem.Kind == ProblemNoConsumer && !strictOutputs:
+			outputs = append(outputs, problem.Channel)
+		default:
+			problems = append(problems, problem)
+		}
+	}
+
+	if err := validationError(problems); err != nil {
+		return err
+	}
+
+	if len(inputs) > 0 || len(outputs) > 0 {
+		c.opts.logger.Debug("conveyer: treating undeclared channels as endpoints",
+			"inputs", inputs, "outputs", outputs)
+	}
+
+	return nil
+}
+
+func (c *Conveyer[T]) reject(problem Problem) {
+	c.mu.Lock()
+	c.rejected = append(c.rejected, problem)
+	c.mu.Unlock()
+
+	c.opts.logger.Error("conveyer: configuration rejected", "problem", problem.Kind, "detail", problem.Detail)
+}
+
+func validationError(problems []Problem) error {
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+func (c *Conveyer[T]) check() ([]Problem, []Problem) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	writers := make(map[string][]string)
+	readers := make(map[string][]string)
+
+	for _, name := range c.inputs {
+		writers[name] = append(writers[name], "input")
+	}
+
+	for _, name := range c.outputs {
+		readers[name] = append(readers[name], "output")
+	}
+
+	for _, name := range sortedKeys(c.channels) {
+		current := c.channels[name]
+
+		if current.deadLetter {
+			writers[name] = append(writers[name], "dead letters")
+			readers[name] = append(readers[name], "dead letters")
+		}
+
+		if current.sent.Load() && !contains(c.inputs, name) {
+			writers[name] = append(writers[name], "send")
+		}
+
+		if current.received.Load() && !contains(c.outputs, name) {
+			readers[name] = append(readers[name], "recv")
+		}
+	}
+
+	for _, current := range c.stages {
+		for _, name := range current.inputs {
+			readers[name] = append(readers[name], current.name)
+		}
+
+		for _, name := range current.outputs {
+			writers[name] = append(writers[name], current.name)
+		}
+	}
+
+	problems := make([]Problem, 0)
+	undeclared := make([]Problem, 0)
+
+	for _, name := range sortedKeys(c.channels) {
+		if len(writers[name]) == 0 {
+			undeclared = append(undeclared, Problem{
+				Kind:    ProblemNoProducer,
+				Channel: name,
+				Detail:  "is neither a declared input nor written by a stage",
+			})
+		}
+
+		if len(readers[name]) == 0 {
+			undeclared = append(undeclared, Problem{
+				Kind:    ProblemNoConsumer,
+				Channel: name,
+				Detail:  "is neither a declared output nor read by a stage",
+			})
+		}
+
+		if len(writers[name]) > 1 {
+			problems = append(problems, Problem{
+				Kind:    ProblemDuplicateWriters,
+				Channel: name,
+				Detail:  "is written by " + strings.Join(writers[name], ", "),
+			})
+		}
+	}
+
+	names := make(map[string]int)
+
+	for _, current := range c.stages {
+		names[current.name]++
+
+		if names[current.name] == 2 {
+			problems = append(problems, Problem{
+				Kind:    ProblemDuplicateStage,
+				Channel: "",
+				Detail:  current.name,
+			})
+		}
+	}
+
+	for _, current := range c.stages {
+		if current.policy.Action != FailFast && !perMessage(current.kind) {
+			problems = append(problems, Problem{
+				Kind:    ProblemMessagePolicy,
+				Channel: "",
+				Detail:  current.name + " does not map each output to one input message",
+			})
+		}
+	}
+
+	for _, cycle := range findCycles(c.stages) {
+		problems = append(problems, Problem{
+			Kind:    ProblemCycle,
+			Channel: cycle[0],
+			Detail:  "loops through " + strings.Join(cycle, " -> "),
+		})
+	}
+
+	for _, name := range c.late {
+		problems = append(problems, Problem{
+			Kind:    ProblemRegisteredLate,
+			Channel: "",
+			Detail:  name,
+		})
+	}
+
+	problems = append(problems, c.rejected...)
+
+	return problems, undeclared
+}
+
+func findCycles[T any](stages []stage[T]) [][]string {
+	next := make(map[string][]string)
+
+	for _, current := range stages {
+		for _, input := range current.inputs {
+			next[input] = append(next[input], current.outputs...)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int)
+	path := make([]string, 0)
+	cycles := make([][]string, 0)
+
+	var visit func(name string)
+
+	visit = func(name string) {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, target := range next[name] {
+			switch state[target] {
+			case visiting:
+				start := indexOf(path, target)
+				cycle := append(append([]string(nil), path[start:]...), target)
+				cycles = append(cycles, cycle)
+			case unvisited:
+				visit(target)
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+
+	for _, name := range sortedKeys(next) {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+
+	return cycles
+}
+
+func contains(list []string, value string) bool {
+	return indexOf(list, value) >= 0
+}
+
+func indexOf(list []string, value string) int {
+	for index, item := range list {
+		if item == value {
+			return index
+		}
+	}
+
+	return -1
+}
+
+func sortedKeys[V any](items map[string]V) []string {
+	keys := make([]string, 0, len(items))
+
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
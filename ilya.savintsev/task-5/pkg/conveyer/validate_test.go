@@ -0,0 +1,149 @@
+package conveyer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func TestValidateDanglingChannel(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "mid")
+	conv.RegisterSeparator(handlers.SeparatorFunc, "mid", []string{"out", "ouy"})
+	conv.DeclareInputs("in")
+	conv.DeclareOutputs("out")
+
+	var validationErr *conveyer.ValidationError
+	if err := conv.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	if len(validationErr.Problems) != 1 || validationErr.Problems[0].Channel != "ouy" {
+		t.Fatalf("unexpected problems: %+v", validationErr.Problems)
+	}
+
+	if err := conv.Run(context.Background()); !errors.Is(err, conveyer.ErrInvalidGraph) {
+		t.Fatalf("expected run to refuse invalid graph, got %v", err)
+	}
+}
+
+func TestValidateCycleAndDuplicateWriters(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "a", "b")
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "b", "a")
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "c", "b")
+
+	var validationErr *conveyer.ValidationError
+	if err := conv.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	kinds := make(map[conveyer.ProblemKind]int)
+	for _, problem := range validationErr.Problems {
+		kinds[problem.Kind]++
+	}
+
+	if kinds[conveyer.ProblemCycle] != 1 || kinds[conveyer.ProblemDuplicateWriters] != 1 {
+		t.Fatalf("unexpected problems: %+v", validationErr.Problems)
+	}
+}
+
+func TestValidateWithoutDeclarations(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1)
+	conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"in", "b"}, "bb")
+
+	if err := conv.RegisterChannel("loose", 1); err != nil {
+		t.Fatalf("unexpected register error: %v", err)
+	}
+
+	for _, name := range []string{"in", "loose"} {
+		if err := conv.Send(name, "x"); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	if _, err := conv.Recv("loose"); err != nil {
+		t.Fatalf("unexpected recv error: %v", err)
+	}
+
+	var validationErr *conveyer.ValidationError
+	if err := conv.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	got := make(map[string]conveyer.ProblemKind)
+	for _, problem := range validationErr.Problems {
+		got[problem.Channel] = problem.Kind
+	}
+
+	if len(got) != 2 || got["b"] != conveyer.ProblemNoProducer || got["bb"] != conveyer.ProblemNoConsumer {
+		t.Fatalf("unexpected problems: %+v", validationErr.Problems)
+	}
+}
+
+func TestRegisterAfterRunIsLogged(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+
+	conv := conveyer.New(1, conveyer.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = conv.Run(ctx)
+	}()
+
+	if err := conv.Send("in", "a"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if _, err := conv.Recv("out"); err != nil {
+		t.Fatalf("unexpected recv error: %v", err)
+	}
+
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "out", "late")
+
+	if !strings.Contains(logs.String(), "stage registered after run is ignored") ||
+		!strings.Contains(logs.String(), `stage="decorator out -> late"`) {
+		t.Fatalf("late registration was not logged:\n%s", logs.String())
+	}
+}
+
+func TestUndeclaredEndpointsLogOnlyAtDebug(t *testing.T) {
+	t.Parallel()
+
+	for level, logged := range map[slog.Level]bool{slog.LevelInfo: false, slog.LevelDebug: true} {
+		var logs bytes.Buffer
+
+		handler := slog.NewTextHandler(&logs, &slog.HandlerOptions{AddSource: false, Level: level, ReplaceAttr: nil})
+
+		conv := conveyer.New(1, conveyer.WithLogger(slog.New(handler)))
+		conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := conv.Run(ctx); err != nil {
+			t.Fatalf("unexpected run error: %v", err)
+		}
+
+		if got := strings.Contains(logs.String(), "treating undeclared channels as endpoints"); got != logged {
+			t.Fatalf("level %s: logged = %t, want %t:\n%s", level, got, logged, logs.String())
+		}
+	}
+}
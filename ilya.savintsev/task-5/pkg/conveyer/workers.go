@@ -0,0 +1,240 @@
+package conveyer
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+type worker[T any] struct {
+	inputs   []chan T
+	closed   []bool
+	outputs  []chan T
+	done     chan error
+	cancel   context.CancelFunc
+	inflight [][]*delivery[T]
+	running  bool
+}
+
+func WithWorkers(workers int) StageOption {
+	return func(opts *stageOptions) {
+		opts.workers = workers
+	}
+}
+
+func WithOrderedOutput() StageOption {
+	return func(opts *stageOptions) {
+		opts.ordered = true
+	}
+}
+
+func newWorkers[T any](count, inputs int) []*worker[T] {
+	workers := make([]*worker[T], 0, count)
+
+	for range count {
+		workers = append(workers, &worker[T]{
+			inputs:   nil,
+			closed:   nil,
+			outputs:  nil,
+			done:     nil,
+			cancel:   nil,
+			inflight: make([][]*delivery[T], inputs),
+			running:  false,
+		})
+	}
+
+	return workers
+}
+
+func (r *stageRun[T]) start(ctx context.Context, current *worker[T]) {
+	inputs := make([]chan T, len(r.sources))
+	closed := make([]bool, len(r.sources))
+
+	for index := range inputs {
+		inputs[index] = make(chan T)
+
+		if r.exhausted[index] && len(r.queued[index]) == 0 {
+			closed[index] = true
+			close(inputs[index])
+		}
+	}
+
+	r.launch(ctx, current, inputs, closed)
+}
+
+func (r *stageRun[T]) launch(ctx context.Context, current *worker[T], inputs []chan T, closed []bool) {
+	handlerCtx, cancel := context.WithCancel(ctx)
+
+	outputs := make([]chan T, len(r.targets))
+	for index := range outputs {
+		outputs[index] = make(chan T)
+	}
+
+	done := make(chan error, 1)
+
+	current.inputs, current.closed, current.outputs = inputs, closed, outputs
+	current.done, current.cancel, current.running = done, cancel, true
+
+	handlerFunc := r.stage.fn
+
+	go func() {
+		done <- handlerFunc(handlerCtx, inputs, outputs)
+	}()
+}
+
+func (r *stageRun[T]) accepts(current *worker[T], index int) bool {
+	if !r.ordered || len(r.reorder) < 2*len(r.workers) || r.queued[index][0].attempts > 0 {
+		return true
+	}
+
+	for _, handed := range current.held() {
+		if !handed.complete {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *stageRun[T]) handed(ctx context.Context, current *worker[T], handed *delivery[T]) {
+	handed.handed = time.Now()
+
+	if r.ordered && handed.attempts == 0 {
+		r.reorder = append(r.reorder, handed)
+	}
+
+	held := current.inflight[handed.input]
+	if perMessage(r.stage.kind) {
+		r.completed(ctx, held)
+
+		held = nil
+	}
+
+	current.inflight[handed.input] = append(held, handed)
+
+	if r.breaker != nil {
+		r.breaker.handed()
+	}
+}
+
+func (w *worker[T]) held() []*delivery[T] {
+	held := make([]*delivery[T], 0, len(w.inflight))
+
+	for _, queue := range w.inflight {
+		held = append(held, queue...)
+	}
+
+	return held
+}
+
+func (w *worker[T]) origin() *delivery[T] {
+	held := w.held()
+	if len(held) == 1 {
+		return held[0]
+	}
+
+	var fresh *delivery[T]
+
+	for _, handed := range held {
+		if handed.emitted {
+			continue
+		}
+
+		if fresh != nil {
+			return nil
+		}
+
+		fresh = handed
+	}
+
+	return fresh
+}
+
+func (r *stageRun[T]) attribute(current *worker[T], data T) (*delivery[T], []*delivery[T]) {
+	switch {
+	case aggregating(r.stage.kind):
+		return current.origin(), current.take()
+	case r.stage.kind == kindMultiplexer:
+		from := current.match(data)
+
+		return from, current.through(from)
+	}
+
+	from := current.origin()
+	if from == nil {
+		return nil, nil
+	}
+
+	return from, []*delivery[T]{from}
+}
+
+func (w *worker[T]) match(data T) *delivery[T] {
+	var oldest *delivery[T]
+
+	for _, handed := range w.held() {
+		if handed.emitted || !reflect.DeepEqual(handed.msg.data, data) {
+			continue
+		}
+
+		if oldest == nil || handed.handed.Before(oldest.handed) {
+			oldest = handed
+		}
+	}
+
+	return oldest
+}
+
+func (w *worker[T]) through(last *delivery[T]) []*delivery[T] {
+	if last == nil {
+		return nil
+	}
+
+	queue := w.inflight[last.input]
+
+	for position, handed := range queue {
+		if handed == last {
+			w.inflight[last.input] = queue[position+1:]
+
+			return queue[:position+1]
+		}
+	}
+
+	return nil
+}
+
+func (w *worker[T]) take() []*delivery[T] {
+	held := w.held()
+
+	for index := range w.inflight {
+		w.inflight[index] = nil
+	}
+
+	return held
+}
+
+func (r *stageRun[T]) completed(ctx context.Context, inflight []*delivery[T]) {
+	if !r.ordered {
+		for _, handed := range inflight {
+			r.settle(handed)
+		}
+
+		return
+	}
+
+	for _, handed := range inflight {
+		handed.complete = true
+	}
+
+	for len(r.reorder) > 0 && r.reorder[0].complete {
+		head := r.reorder[0]
+		r.reorder = r.reorder[1:]
+
+		for _, produced := range head.outputs {
+			if err := r.put(ctx, produced.index, produced.data, head); err != nil {
+				head.unsaved = true
+			}
+		}
+
+		r.settle(head)
+	}
+}
@@ -0,0 +1,381 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/conveyertest"
+)
+
+const workerCount = 4
+
+func slowDecorator(ctx context.Context, input chan string, output chan string) error {
+	for data := range input {
+		number, _ := strconv.Atoi(data)
+		time.Sleep(time.Duration(number%3) * 100 * time.Microsecond)
+
+		select {
+		case output <- data:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func startPool(tb testing.TB, opts ...conveyer.StageOption) *conveyer.DefaultConveyer {
+	tb.Helper()
+
+	conv := conveyer.New(workerCount)
+	conv.RegisterDecoratorWithOptions(slowDecorator, "in", "out", opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tb.Cleanup(cancel)
+
+	go func() {
+		_ = conv.Run(ctx)
+	}()
+
+	return conv
+}
+
+func pushThrough(tb testing.TB, conv *conveyer.DefaultConveyer, count int) []string {
+	tb.Helper()
+
+	go func() {
+		for index := range count {
+			if err := conv.SendContext(context.Background(), "in", strconv.Itoa(index)); err != nil {
+				return
+			}
+		}
+	}()
+
+	result := make([]string, 0, count)
+
+	for range count {
+		data, err := conv.Recv("out")
+		if err != nil {
+			tb.Fatalf("unexpected recv error: %v", err)
+		}
+
+		result = append(result, data)
+	}
+
+	return result
+}
+
+func TestOrderedWorkersKeepOrder(t *testing.T) {
+	t.Parallel()
+
+	const messages = 100
+
+	conv := startPool(t, conveyer.WithWorkers(workerCount), conveyer.WithOrderedOutput())
+
+	for index, data := range pushThrough(t, conv, messages) {
+		if data != strconv.Itoa(index) {
+			t.Fatalf("message %d out of order: %q", index, data)
+		}
+	}
+}
+
+func TestOrderedWorkersAreLongLived(t *testing.T) {
+	t.Parallel()
+
+	const messages = 100
+
+	var invocations atomic.Int32
+
+	counted := func(ctx context.Context, input chan string, output chan string) error {
+		invocations.Add(1)
+
+		return slowDecorator(ctx, input, output)
+	}
+
+	conv := conveyer.New(workerCount)
+	conv.RegisterDecoratorWithOptions(counted, "in", "out", conveyer.WithWorkers(workerCount),
+		conveyer.WithOrderedOutput())
+	runConveyer(t, conv)
+
+	for index, data := range pushThrough(t, conv, messages) {
+		if data != strconv.Itoa(index) {
+			t.Fatalf("message %d out of order: %q", index, data)
+		}
+	}
+
+	if got := invocations.Load(); got != workerCount {
+		t.Fatalf("handler started %d times, want %d", got, workerCount)
+	}
+}
+
+func TestOrderedWorkersKeepOrderAcrossDrops(t *testing.T) {
+	t.Parallel()
+
+	const messages = 100
+
+	conv := conveyer.New(workerCount)
+	conv.RegisterDecoratorWithOptions(dropMultiplesOfThree, "in", "out",
+		conveyer.WithWorkers(workerCount), conveyer.WithOrderedOutput())
+	runConveyer(t, conv)
+
+	go func() {
+		for index := range messages {
+			if err := conv.SendContext(context.Background(), "in", strconv.Itoa(index)); err != nil {
+				return
+			}
+		}
+
+		_ = conv.CloseInput("in")
+	}()
+
+	got := recvAll(t, conv, "out", messages-(messages+2)/3)
+	for position := 1; position < len(got); position++ {
+		if mustAtoi(t, got[position]) <= mustAtoi(t, got[position-1]) {
+			t.Fatalf("output out of order: %v", got)
+		}
+	}
+}
+
+func dropMultiplesOfThree(ctx context.Context, input chan string, output chan string) error {
+	for {
+		select {
+		case data, ok := <-input:
+			if !ok {
+				return nil
+			}
+
+			if number, _ := strconv.Atoi(data); number%3 == 0 {
+				continue
+			}
+
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func TestUnorderedWorkersDeliverEveryMessageOnce(t *testing.T) {
+	t.Parallel()
+
+	const messages = 200
+
+	conv := startPool(t, conveyer.WithWorkers(workerCount))
+
+	seen := make(map[string]int, messages)
+	for _, data := range pushThrough(t, conv, messages) {
+		seen[data]++
+	}
+
+	for index := range messages {
+		if count := seen[strconv.Itoa(index)]; count != 1 {
+			t.Fatalf("message %d delivered %d times", index, count)
+		}
+	}
+}
+
+var errUnlucky = errors.New("unlucky")
+
+func TestWorkerFailureReportsFailingPayload(t *testing.T) {
+	t.Parallel()
+
+	const (
+		messages = 50
+		failing  = "13"
+	)
+
+	unlucky := func(ctx context.Context, input chan string, output chan string) error {
+		for data := range input {
+			if data == failing {
+				return errUnlucky
+			}
+
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered=%t", ordered), func(t *testing.T) {
+			t.Parallel()
+
+			opts := []conveyer.StageOption{
+				conveyer.WithWorkers(workerCount),
+				conveyer.WithErrorPolicy(conveyer.SkipPolicy()),
+			}
+			if ordered {
+				opts = append(opts, conveyer.WithOrderedOutput())
+			}
+
+			conv := conveyer.New(workerCount)
+			conv.RegisterDecoratorWithOptions(unlucky, "in", "out", opts...)
+			runConveyer(t, conv)
+
+			go func() {
+				for index := range messages {
+					if err := conv.SendContext(context.Background(), "in", strconv.Itoa(index)); err != nil {
+						return
+					}
+				}
+			}()
+
+			got := recvAll(t, conv, "out", messages-1)
+
+			seen := make(map[string]bool, len(got))
+			for position, data := range got {
+				if seen[data] || data == failing {
+					t.Fatalf("unexpected output %q in %v", data, got)
+				}
+
+				seen[data] = true
+
+				if ordered && position > 0 && mustAtoi(t, data) < mustAtoi(t, got[position-1]) {
+					t.Fatalf("output out of order: %v", got)
+				}
+			}
+
+			failures := conv.Failures()
+			if len(failures) != 1 || failures[0].Payload != failing || !errors.Is(failures[0].Err, errUnlucky) {
+				t.Fatalf("unexpected failures: %+v", failures)
+			}
+		})
+	}
+}
+
+func mustAtoi(t *testing.T, data string) int {
+	t.Helper()
+
+	number, err := strconv.Atoi(data)
+	if err != nil {
+		t.Fatalf("unexpected payload %q: %v", data, err)
+	}
+
+	return number
+}
+
+func BenchmarkDecoratorSingleWorker(b *testing.B) {
+	pushThrough(b, startPool(b), b.N)
+}
+
+func BenchmarkDecoratorOrderedWorkers(b *testing.B) {
+	pushThrough(b, startPool(b, conveyer.WithWorkers(workerCount), conveyer.WithOrderedOutput()), b.N)
+}
+
+func BenchmarkDecoratorUnorderedWorkers(b *testing.B) {
+	pushThrough(b, startPool(b, conveyer.WithWorkers(workerCount)), b.N)
+}
+
+func gatedDecorator(
+	started chan<- struct{},
+	gate <-chan struct{},
+	fail func(string) bool,
+) conveyer.DecoratorFunc[string] {
+	return func(ctx context.Context, input chan string, output chan string) error {
+		for data := range input {
+			if fail(data) {
+				return errFlaky
+			}
+
+			if data == "slow" {
+				started <- struct{}{}
+				<-gate
+			}
+
+			select {
+			case output <- data:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	}
+}
+
+func holdSlowThenFail(t *testing.T, conv *conveyer.DefaultConveyer, started <-chan struct{}) {
+	t.Helper()
+
+	if err := conv.Send("in", "slow"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	<-started
+
+	if err := conv.Send("in", "bad"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	for len(conv.Failures()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRetryBackoffDoesNotBlockOtherWorkers(t *testing.T) {
+	t.Parallel()
+
+	clk := conveyertest.NewFakeClock(time.Unix(0, 0))
+	started, gate := make(chan struct{}), make(chan struct{})
+
+	var failed atomic.Bool
+
+	conv := conveyer.New(4, conveyer.WithClock(clk))
+	conv.RegisterDecoratorWithOptions(gatedDecorator(started, gate, func(data string) bool {
+		return data == "bad" && !failed.Swap(true)
+	}), "in", "out", conveyer.WithWorkers(2), conveyer.WithErrorPolicy(conveyer.RetryPolicy(1, time.Hour)))
+	runConveyer(t, conv)
+
+	holdSlowThenFail(t, conv, started)
+	close(gate)
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "slow" {
+		t.Fatalf("unexpected output while retry is pending: %v", got)
+	}
+
+	clk.Advance(time.Hour)
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "bad" {
+		t.Fatalf("unexpected output after backoff: %v", got)
+	}
+}
+
+func TestFullDeadLetterDoesNotBlockOtherWorkers(t *testing.T) {
+	t.Parallel()
+
+	started, gate := make(chan struct{}), make(chan struct{})
+
+	conv := conveyer.New(4)
+	if err := conv.RegisterChannel("dead", 0); err != nil {
+		t.Fatalf("unexpected register error: %v", err)
+	}
+
+	conv.RegisterDecoratorWithOptions(gatedDecorator(started, gate, func(data string) bool {
+		return data == "bad"
+	}), "in", "out", conveyer.WithWorkers(2), conveyer.WithErrorPolicy(conveyer.DeadLetterPolicy("dead")))
+	runConveyer(t, conv)
+
+	holdSlowThenFail(t, conv, started)
+	close(gate)
+
+	if got := recvAll(t, conv, "out", 1); got[0] != "slow" {
+		t.Fatalf("unexpected output while dead letter is pending: %v", got)
+	}
+
+	if got := recvAll(t, conv, "dead", 1); got[0] != "bad" {
+		t.Fatalf("unexpected dead letter: %v", got)
+	}
+}
@@ -0,0 +1,193 @@
+package conveyertest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/clock"
+)
+
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	clock  *FakeClock
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+	active bool
+}
+
+type fakeTicker struct {
+	waiter *fakeWaiter
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	fake := &FakeClock{
+		mu:      sync.Mutex{},
+		cond:    nil,
+		now:     start,
+		waiters: []*fakeWaiter{},
+	}
+	fake.cond = sync.NewCond(&fake.mu)
+
+	return fake
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	return f.add(d, 0)
+}
+
+func (f *FakeClock) NewTicker(d time.Duration) clock.Ticker {
+	return fakeTicker{waiter: f.add(d, d)}
+}
+
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	target := f.now.Add(d)
+
+	for {
+		next := f.nextDue(target)
+		if next == nil {
+			break
+		}
+
+		f.now = next.at
+
+		select {
+		case next.ch <- next.at:
+		default:
+		}
+
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			f.remove(next)
+		}
+	}
+
+	f.now = target
+}
+
+func (f *FakeClock) BlockUntil(waiters int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.waiters) < waiters {
+		f.cond.Wait()
+	}
+}
+
+func (f *FakeClock) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.waiters)
+}
+
+func (f *FakeClock) add(d, period time.Duration) *fakeWaiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	waiter := &fakeWaiter{
+		clock:  f,
+		at:     f.now.Add(d),
+		period: period,
+		ch:     make(chan time.Time, 1),
+		active: false,
+	}
+	f.insert(waiter)
+
+	return waiter
+}
+
+func (f *FakeClock) nextDue(target time.Time) *fakeWaiter {
+	if len(f.waiters) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].at.Before(f.waiters[j].at)
+	})
+
+	if f.waiters[0].at.After(target) {
+		return nil
+	}
+
+	return f.waiters[0]
+}
+
+func (f *FakeClock) insert(waiter *fakeWaiter) {
+	waiter.active = true
+	f.waiters = append(f.waiters, waiter)
+	f.cond.Broadcast()
+}
+
+func (f *FakeClock) remove(waiter *fakeWaiter) bool {
+	if !waiter.active {
+		return false
+	}
+
+	waiter.active = false
+
+	for index, current := range f.waiters {
+		if current == waiter {
+			f.waiters = append(f.waiters[:index], f.waiters[index+1:]...)
+
+			break
+		}
+	}
+
+	f.cond.Broadcast()
+
+	return true
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	return w.clock.remove(w)
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	wasActive := w.clock.remove(w)
+	w.at = w.clock.now.Add(d)
+
+	if w.period > 0 {
+		w.period = d
+	}
+
+	w.clock.insert(w)
+
+	return wasActive
+}
+
+func (t fakeTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t fakeTicker) Stop() {
+	t.waiter.Stop()
+}
@@ -0,0 +1,172 @@
+package conveyertest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/conveyertest"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func TestRunDecorator(t *testing.T) {
+	t.Parallel()
+
+	got, err := conveyertest.RunDecorator(t, handlers.PrefixDecoratorFunc, []string{"a", "decorated: b"})
+	if err != nil {
+		t.Fatalf("unexpected handler error: %v", err)
+	}
+
+	if !slices.Equal(got, []string{"decorated: a", "decorated: b"}) {
+		t.Fatalf("unexpected output: %v", got)
+	}
+
+	got, err = conveyertest.RunDecorator(t, handlers.PrefixDecoratorFunc, []string{"a", "no decorator", "c"})
+	if !errors.Is(err, handlers.ErrNoDecorator) {
+		t.Fatalf("unexpected handler error: %v", err)
+	}
+
+	if !slices.Equal(got, []string{"decorated: a"}) {
+		t.Fatalf("unexpected output before failure: %v", got)
+	}
+}
+
+func TestRunMultiplexer(t *testing.T) {
+	t.Parallel()
+
+	got, err := conveyertest.RunMultiplexer(t, handlers.MultiplexerFunc,
+		[][]string{{"a", "b"}, {"no multiplexer", "c"}})
+	if err != nil {
+		t.Fatalf("unexpected handler error: %v", err)
+	}
+
+	slices.Sort(got)
+
+	if !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("unexpected output: %v", got)
+	}
+}
+
+func TestRunSeparator(t *testing.T) {
+	t.Parallel()
+
+	got, err := conveyertest.RunSeparator(t, handlers.SeparatorFunc, []string{"a", "b", "c"}, 2)
+	if err != nil {
+		t.Fatalf("unexpected handler error: %v", err)
+	}
+
+	if !slices.Equal(got[0], []string{"a", "c"}) || !slices.Equal(got[1], []string{"b"}) {
+		t.Fatalf("unexpected output: %v", got)
+	}
+}
+
+func TestBatchWithFakeClock(t *testing.T) {
+	t.Parallel()
+
+	clk := conveyertest.NewFakeClock(time.Unix(0, 0))
+	stage := conveyertest.StartDecorator(t, handlers.BatchDecoratorWithClock(clk, 10, time.Minute,
+		func(items []string) string {
+			return strings.Join(items, "+")
+		}))
+
+	stage.Send(0, "a")
+	clk.BlockUntil(1)
+	stage.Send(0, "b")
+
+	clk.Advance(59 * time.Second)
+	stage.Send(0, "c")
+
+	clk.Advance(time.Second)
+
+	if got := stage.Recv(0); got != "a+b+c" {
+		t.Fatalf("unexpected batch: %q", got)
+	}
+
+	rest, err := stage.Close()
+	if err != nil || len(rest[0]) != 0 {
+		t.Fatalf("unexpected close result: %v, %v", rest, err)
+	}
+}
+
+func TestWindowWithFakeClock(t *testing.T) {
+	t.Parallel()
+
+	clk := conveyertest.NewFakeClock(time.Unix(0, 0))
+	stage := conveyertest.StartDecorator(t, handlers.WindowDecoratorWithClock(clk, 2*time.Second, time.Second,
+		func(items []string) string {
+			return strings.Join(items, ",")
+		}))
+
+	clk.BlockUntil(1)
+
+	stage.Send(0, "a")
+	stage.Send(0, "b")
+	clk.Advance(time.Second)
+
+	if got := stage.Recv(0); got != "a,b" {
+		t.Fatalf("unexpected first window: %q", got)
+	}
+
+	clk.Advance(time.Second)
+	stage.Send(0, "c")
+	stage.Send(0, "d")
+	clk.Advance(time.Second)
+
+	if got := stage.Recv(0); got != "c,d" {
+		t.Fatalf("unexpected second window: %q", got)
+	}
+
+	if _, err := stage.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+}
+
+func TestConveyerWithFakeClock(t *testing.T) {
+	t.Parallel()
+
+	clk := conveyertest.NewFakeClock(time.Unix(0, 0))
+
+	conv := conveyer.New(0, conveyer.WithClock(clk))
+	if err := conv.RegisterChannel("in", 0); err != nil {
+		t.Fatalf("unexpected register error: %v", err)
+	}
+
+	sent := make(chan error, 1)
+
+	go func() {
+		_, err := conv.SendWithPolicy(context.Background(), "in", "late",
+			conveyer.SendPolicy{Mode: conveyer.SendTimeout, Timeout: time.Hour})
+		sent <- err
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(time.Hour)
+
+	select {
+	case err := <-sent:
+		if !errors.Is(err, conveyer.ErrTimeout) {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("send did not time out on the fake clock")
+	}
+}
+
+func TestRunTopologyGolden(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(2)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "mid")
+	conv.RegisterSeparator(handlers.SeparatorFunc, "mid", []string{"left", "right"})
+
+	got := conveyertest.RunTopology(t, conv, conveyertest.Script[string]{
+		Inputs:  map[string][]string{"in": {"one", "two", "three"}},
+		Outputs: []string{"left", "right"},
+	})
+
+	conveyertest.AssertGolden(t, "testdata/prefix_split.golden", got)
+}
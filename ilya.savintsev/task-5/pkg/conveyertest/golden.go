@@ -0,0 +1,158 @@
+package conveyertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+)
+
+const (
+	UpdateEnv      = "CONVEYERTEST_UPDATE"
+	goldenDirPerm  = 0o755
+	goldenFilePerm = 0o644
+)
+
+type Script[T any] struct {
+	Inputs  map[string][]T
+	Outputs []string
+}
+
+func RunTopology[T any](t testing.TB, conv *conveyer.Conveyer[T], script Script[T], opts ...Option) map[string][]T {
+	t.Helper()
+
+	current := newSettings(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), current.timeout)
+	defer cancel()
+
+	stopped := make(chan error, 1)
+
+	go func() {
+		err := conv.Run(ctx)
+		if err != nil {
+			cancel()
+		}
+
+		stopped <- err
+	}()
+
+	var (
+		mu      sync.Mutex
+		workers sync.WaitGroup
+		failure error
+		results = make(map[string][]T, len(script.Outputs))
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failure == nil {
+			failure = err
+		}
+	}
+
+	for _, output := range script.Outputs {
+		results[output] = []T{}
+
+		workers.Add(1)
+
+		go func() {
+			defer workers.Done()
+
+			for {
+				data, err := conv.RecvContext(ctx, output)
+				if errors.Is(err, conveyer.ErrChannelClosed) {
+					return
+				}
+
+				if err != nil {
+					fail(err)
+
+					return
+				}
+
+				mu.Lock()
+				results[output] = append(results[output], data)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for input, items := range script.Inputs {
+		workers.Add(1)
+
+		go func() {
+			defer workers.Done()
+
+			for _, item := range items {
+				if err := conv.SendContext(ctx, input, item); err != nil {
+					fail(err)
+
+					return
+				}
+			}
+
+			if err := conv.CloseInput(input); err != nil {
+				fail(err)
+			}
+		}()
+	}
+
+	workers.Wait()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("topology finished with error: %v", err)
+		}
+	case <-time.After(current.timeout):
+		t.Fatalf("topology did not finish within %v", current.timeout)
+	}
+
+	if failure != nil {
+		t.Fatalf("topology run failed: %v", failure)
+	}
+
+	return results
+}
+
+func AssertGolden(t testing.TB, path string, got any) {
+	t.Helper()
+
+	actual, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("encode golden value: %v", err)
+	}
+
+	actual = append(actual, '\n')
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), goldenDirPerm); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+
+		if err := os.WriteFile(path, actual, goldenFilePerm); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (set %s=1 to create it): %v", UpdateEnv, err)
+	}
+
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("output differs from %s:\n--- want\n%s\n--- got\n%s", path, expected, actual)
+	}
+}
@@ -0,0 +1,306 @@
+package conveyertest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+const DefaultTimeout = time.Second
+
+type Option func(*settings)
+
+type settings struct {
+	timeout time.Duration
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *settings) {
+		opts.timeout = timeout
+	}
+}
+
+func newSettings(opts []Option) settings {
+	current := settings{timeout: DefaultTimeout}
+
+	for _, opt := range opts {
+		opt(&current)
+	}
+
+	return current
+}
+
+func RunDecorator[T any](
+	t testing.TB,
+	handler func(context.Context, chan T, chan T) error,
+	inputs []T,
+	opts ...Option,
+) ([]T, error) {
+	t.Helper()
+
+	results, err := run(t, func(ctx context.Context, ins []chan T, outs []chan T) error {
+		return handler(ctx, ins[0], outs[0])
+	}, [][]T{inputs}, 1, opts)
+
+	return results[0], err
+}
+
+func RunMultiplexer[T any](
+	t testing.TB,
+	handler func(context.Context, []chan T, chan T) error,
+	inputs [][]T,
+	opts ...Option,
+) ([]T, error) {
+	t.Helper()
+
+	results, err := run(t, func(ctx context.Context, ins []chan T, outs []chan T) error {
+		return handler(ctx, ins, outs[0])
+	}, inputs, 1, opts)
+
+	return results[0], err
+}
+
+func RunSeparator[T any](
+	t testing.TB,
+	handler func(context.Context, chan T, []chan T) error,
+	inputs []T,
+	outputs int,
+	opts ...Option,
+) ([][]T, error) {
+	t.Helper()
+
+	return run(t, func(ctx context.Context, ins []chan T, outs []chan T) error {
+		return handler(ctx, ins[0], outs)
+	}, [][]T{inputs}, outputs, opts)
+}
+
+func run[T any](
+	t testing.TB,
+	handler func(context.Context, []chan T, []chan T) error,
+	inputs [][]T,
+	outputs int,
+	opts []Option,
+) ([][]T, error) {
+	t.Helper()
+
+	current := newSettings(opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ins := make([]chan T, len(inputs))
+	for index := range ins {
+		ins[index] = make(chan T)
+	}
+
+	outs := make([]chan T, outputs)
+	for index := range outs {
+		outs[index] = make(chan T)
+	}
+
+	finished := make(chan error, 1)
+
+	go func() {
+		finished <- handler(ctx, ins, outs)
+	}()
+
+	stopFeed := make(chan struct{})
+
+	var feeders sync.WaitGroup
+
+	for index, items := range inputs {
+		feeders.Add(1)
+
+		go func() {
+			defer feeders.Done()
+			defer close(ins[index])
+
+			for _, item := range items {
+				select {
+				case ins[index] <- item:
+				case <-stopFeed:
+					return
+				}
+			}
+		}()
+	}
+
+	results := make([][]T, outputs)
+
+	var collectors sync.WaitGroup
+
+	for index := range outs {
+		collectors.Add(1)
+
+		go func() {
+			defer collectors.Done()
+
+			for data := range outs[index] {
+				results[index] = append(results[index], data)
+			}
+		}()
+	}
+
+	timer := time.NewTimer(current.timeout)
+	defer timer.Stop()
+
+	var err error
+
+	select {
+	case err = <-finished:
+	case <-timer.C:
+		cancel()
+		<-finished
+		t.Fatalf("handler did not finish within %v", current.timeout)
+	}
+
+	close(stopFeed)
+	feeders.Wait()
+
+	for _, out := range outs {
+		close(out)
+	}
+
+	collectors.Wait()
+
+	return results, err
+}
+
+type Stage[T any] struct {
+	t        testing.TB
+	timeout  time.Duration
+	inputs   []chan T
+	outputs  []chan T
+	cancel   context.CancelFunc
+	finished chan error
+}
+
+func Start[T any](
+	t testing.TB,
+	handler func(context.Context, []chan T, []chan T) error,
+	inputs, outputs int,
+	opts ...Option,
+) *Stage[T] {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stage := &Stage[T]{
+		t:        t,
+		timeout:  newSettings(opts).timeout,
+		inputs:   make([]chan T, inputs),
+		outputs:  make([]chan T, outputs),
+		cancel:   cancel,
+		finished: make(chan error, 1),
+	}
+
+	for index := range stage.inputs {
+		stage.inputs[index] = make(chan T)
+	}
+
+	for index := range stage.outputs {
+		stage.outputs[index] = make(chan T)
+	}
+
+	go func() {
+		stage.finished <- handler(ctx, stage.inputs, stage.outputs)
+	}()
+
+	t.Cleanup(cancel)
+
+	return stage
+}
+
+func StartDecorator[T any](
+	t testing.TB,
+	handler func(context.Context, chan T, chan T) error,
+	opts ...Option,
+) *Stage[T] {
+	t.Helper()
+
+	return Start(t, func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+		return handler(ctx, inputs[0], outputs[0])
+	}, 1, 1, opts...)
+}
+
+func (s *Stage[T]) Send(input int, data T) {
+	s.t.Helper()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	select {
+	case s.inputs[input] <- data:
+	case <-timer.C:
+		s.t.Fatalf("handler did not accept input %d within %v", input, s.timeout)
+	}
+}
+
+func (s *Stage[T]) Recv(output int) T {
+	s.t.Helper()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	select {
+	case data := <-s.outputs[output]:
+		return data
+	case <-timer.C:
+		s.t.Fatalf("handler produced nothing on output %d within %v", output, s.timeout)
+	}
+
+	var zero T
+
+	return zero
+}
+
+func (s *Stage[T]) Close() ([][]T, error) {
+	s.t.Helper()
+
+	defer s.cancel()
+
+	for _, input := range s.inputs {
+		close(input)
+	}
+
+	rest := make([][]T, len(s.outputs))
+	drained := make(chan struct{})
+
+	var drainers sync.WaitGroup
+
+	for index, output := range s.outputs {
+		drainers.Add(1)
+
+		go func() {
+			defer drainers.Done()
+
+			for {
+				select {
+				case data := <-output:
+					rest[index] = append(rest[index], data)
+				case <-drained:
+					return
+				}
+			}
+		}()
+	}
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	var err error
+
+	select {
+	case err = <-s.finished:
+	case <-timer.C:
+		s.cancel()
+		err = <-s.finished
+		s.t.Errorf("handler did not finish within %v", s.timeout)
+	}
+
+	close(drained)
+	drainers.Wait()
+
+	return rest, err
+}
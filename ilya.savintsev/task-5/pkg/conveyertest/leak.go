@@ -0,0 +1,195 @@
+package conveyertest
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const leakPollInterval = 10 * time.Millisecond
+
+var ignoredStacks = []string{
+	"testing.tRunner(",
+	"testing.(*T).Run(",
+	"testing.(*T).Parallel(",
+	"os/signal.signal_recv",
+}
+
+func VerifyNoLeaks(t testing.TB, opts ...Option) {
+	t.Helper()
+
+	current := newSettings(opts)
+	stacks := goroutines()
+	before := make(map[string]struct{})
+
+	for _, stack := range stacks {
+		before[goroutineID(stack)] = struct{}{}
+	}
+
+	tree := &lineage{mu: sync.Mutex{}, root: goroutineID(currentStack()), owned: make(map[string]struct{})}
+	tree.owned[tree.root] = struct{}{}
+
+	stop := make(chan struct{})
+	sampled := make(chan struct{})
+
+	go tree.sample(stop, sampled)
+
+	t.Cleanup(func() {
+		close(stop)
+		<-sampled
+
+		deadline := time.Now().Add(current.timeout)
+
+		for {
+			leaked := tree.leaked(before)
+			if len(leaked) == 0 {
+				return
+			}
+
+			if time.Now().After(deadline) {
+				t.Errorf("%d goroutines leaked:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+
+				return
+			}
+
+			time.Sleep(leakPollInterval)
+		}
+	})
+}
+
+type lineage struct {
+	mu    sync.Mutex
+	root  string
+	owned map[string]struct{}
+}
+
+func (l *lineage) sample(stop <-chan struct{}, sampled chan<- struct{}) {
+	defer close(sampled)
+
+	ticker := time.NewTicker(leakPollInterval)
+	defer ticker.Stop()
+
+	for {
+		l.descendants(goroutines())
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (l *lineage) descendants(stacks []string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for grown := true; grown; {
+		grown = false
+
+		for _, stack := range stacks {
+			id := goroutineID(stack)
+			if _, ok := l.owned[id]; ok {
+				continue
+			}
+
+			if _, ok := l.owned[parentID(stack)]; ok {
+				l.owned[id] = struct{}{}
+				grown = true
+			}
+		}
+	}
+
+	result := make([]string, 0, len(stacks))
+
+	for _, stack := range stacks {
+		if _, ok := l.owned[goroutineID(stack)]; ok && goroutineID(stack) != l.root {
+			result = append(result, stack)
+		}
+	}
+
+	return result
+}
+
+func (l *lineage) leaked(before map[string]struct{}) []string {
+	var leaked []string
+
+	for _, stack := range l.descendants(goroutines()) {
+		if _, ok := before[goroutineID(stack)]; ok || ignored(stack) {
+			continue
+		}
+
+		leaked = append(leaked, stack)
+	}
+
+	return leaked
+}
+
+func ignored(stack string) bool {
+	for _, pattern := range ignoredStacks {
+		if strings.Contains(stack, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func goroutines() []string {
+	buf := make([]byte, 1<<16)
+
+	for {
+		size := runtime.Stack(buf, true)
+		if size < len(buf) {
+			buf = buf[:size]
+
+			break
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := strings.Split(string(bytes.TrimSpace(buf)), "\n\n")
+
+	current := goroutineID(stacks[0])
+	result := make([]string, 0, len(stacks))
+
+	for _, stack := range stacks {
+		if goroutineID(stack) != current {
+			result = append(result, stack)
+		}
+	}
+
+	return result
+}
+
+func currentStack() string {
+	buf := make([]byte, 1<<10)
+
+	return string(buf[:runtime.Stack(buf, false)])
+}
+
+func parentID(stack string) string {
+	_, creator, found := strings.Cut(stack, "\ncreated by ")
+	if !found {
+		return ""
+	}
+
+	line, _, _ := strings.Cut(creator, "\n")
+
+	_, parent, found := strings.Cut(line, " in goroutine ")
+	if !found {
+		return ""
+	}
+
+	return "goroutine " + parent
+}
+
+func goroutineID(stack string) string {
+	header, _, _ := strings.Cut(stack, " [")
+
+	return header
+}
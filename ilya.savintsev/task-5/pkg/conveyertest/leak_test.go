@@ -0,0 +1,117 @@
+package conveyertest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/conveyertest"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+type recorder struct {
+	testing.TB
+
+	cleanups []func()
+	errors   []string
+}
+
+func (r *recorder) Cleanup(fn func()) {
+	r.cleanups = append(r.cleanups, fn)
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) finish() {
+	for index := len(r.cleanups) - 1; index >= 0; index-- {
+		r.cleanups[index]()
+	}
+}
+
+func TestVerifyNoLeaksAfterShutdown(t *testing.T) {
+	conveyertest.VerifyNoLeaks(t)
+
+	conv := conveyer.New(1)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- conv.Run(ctx)
+	}()
+
+	if err := conv.Send("in", "a"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	cancel()
+
+	if err := <-stopped; err != nil {
+		t.Fatalf("unexpected run error: %v", err)
+	}
+}
+
+func TestVerifyNoLeaksReportsLeak(t *testing.T) {
+	rec := &recorder{TB: t, cleanups: nil, errors: nil}
+	conveyertest.VerifyNoLeaks(rec, conveyertest.WithTimeout(50*time.Millisecond))
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		<-release
+	}()
+
+	rec.finish()
+	close(release)
+	<-done
+
+	if len(rec.errors) != 1 {
+		t.Fatalf("expected one leak report, got %v", rec.errors)
+	}
+}
+
+func TestVerifyNoLeaksIgnoresOtherTests(t *testing.T) {
+	rec := &recorder{TB: t, cleanups: nil, errors: nil}
+
+	spawn := make(chan struct{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		<-spawn
+
+		go func() {
+			defer close(done)
+
+			close(started)
+			<-release
+		}()
+	}()
+
+	verified := make(chan struct{})
+
+	go func() {
+		defer close(verified)
+
+		conveyertest.VerifyNoLeaks(rec, conveyertest.WithTimeout(50*time.Millisecond))
+		close(spawn)
+		<-started
+		rec.finish()
+	}()
+
+	<-verified
+	close(release)
+	<-done
+
+	if len(rec.errors) != 0 {
+		t.Fatalf("unexpected leak report: %v", rec.errors)
+	}
+}
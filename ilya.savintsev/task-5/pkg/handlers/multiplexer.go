@@ -2,45 +2,260 @@ package handlers
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"sync"
 )
 
 func MultiplexerFunc(ctx context.Context, inputs []chan string, output chan string) error {
-	if len(inputs) == 0 {
-		return nil
-	}
+	return FilterMultiplexer(func(data string) bool {
+		return !strings.Contains(data, "no multiplexer")
+	})(ctx, inputs, output)
+}
 
-	var waitGroup sync.WaitGroup
+func MergeMultiplexer[T any]() func(context.Context, []chan T, chan T) error {
+	return FilterMultiplexer(func(T) bool { return true })
+}
 
-	waitGroup.Add(len(inputs))
+func FilterMultiplexer[T any](keep func(T) bool) func(context.Context, []chan T, chan T) error {
+	return func(ctx context.Context, inputs []chan T, output chan T) error {
+		if len(inputs) == 0 {
+			return nil
+		}
 
-	for index := range inputs {
-		go func(idx int) {
-			defer waitGroup.Done()
+		var waitGroup sync.WaitGroup
 
-			for {
-				select {
-				case data, ok := <-inputs[idx]:
-					if !ok {
-						return
-					}
+		waitGroup.Add(len(inputs))
 
-					if !strings.Contains(data, "no multiplexer") {
-						select {
-						case output <- data:
-						case <-ctx.Done():
+		for index := range inputs {
+			go func(idx int) {
+				defer waitGroup.Done()
+
+				for {
+					select {
+					case data, ok := <-inputs[idx]:
+						if !ok {
 							return
 						}
+
+						if keep(data) {
+							select {
+							case output <- data:
+							case <-ctx.Done():
+								return
+							}
+						}
+					case <-ctx.Done():
+						return
 					}
-				case <-ctx.Done():
-					return
+				}
+			}(index)
+		}
+
+		waitGroup.Wait()
+
+		return nil
+	}
+}
+
+func PriorityMultiplexer[T any]() func(context.Context, []chan T, chan T) error {
+	return func(ctx context.Context, inputs []chan T, output chan T) error {
+		return mergeByWeights(ctx, inputs, output, nil, true)
+	}
+}
+
+func WeightedMultiplexer[T any](weights ...int) func(context.Context, []chan T, chan T) error {
+	return func(ctx context.Context, inputs []chan T, output chan T) error {
+		return mergeByWeights(ctx, inputs, output, weights, false)
+	}
+}
+
+func OrderedMergeMultiplexer[T any](less func(a, b T) bool) func(context.Context, []chan T, chan T) error {
+	return func(ctx context.Context, inputs []chan T, output chan T) error {
+		heads := make([]T, len(inputs))
+		open := make([]bool, len(inputs))
+
+		for index, input := range inputs {
+			select {
+			case heads[index], open[index] = <-input:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		for {
+			best := -1
+
+			for index := range inputs {
+				if open[index] && (best < 0 || less(heads[index], heads[best])) {
+					best = index
 				}
 			}
-		}(index)
+
+			if best < 0 {
+				return nil
+			}
+
+			select {
+			case output <- heads[best]:
+			case <-ctx.Done():
+				return nil
+			}
+
+			select {
+			case heads[best], open[best] = <-inputs[best]:
+			case <-ctx.Done():
+				return nil
+			}
+		}
 	}
+}
+
+func mergeByWeights[T any](
+	ctx context.Context,
+	inputs []chan T,
+	output chan T,
+	weights []int,
+	strict bool,
+) error {
+	open := make([]bool, len(inputs))
+	for index := range open {
+		open[index] = true
+	}
+
+	for {
+		taken := false
 
-	waitGroup.Wait()
+		for index, input := range inputs {
+			quota := weightOf(weights, index)
+
+			for quota > 0 && open[index] {
+				data, state := tryReceive(input)
+				if state == receiveEmpty {
+					break
+				}
+
+				if state == receiveClosed {
+					open[index] = false
+
+					break
+				}
 
-	return nil
+				if !send(ctx, output, data) {
+					return nil
+				}
+
+				taken = true
+				quota--
+			}
+
+			if strict && taken {
+				break
+			}
+		}
+
+		if taken {
+			continue
+		}
+
+		index, data, ok, cancelled := receiveAny(ctx, inputs, open)
+		if cancelled {
+			return nil
+		}
+
+		if index < 0 {
+			return nil
+		}
+
+		if !ok {
+			open[index] = false
+
+			continue
+		}
+
+		if !send(ctx, output, data) {
+			return nil
+		}
+	}
+}
+
+type receiveState int
+
+const (
+	receiveEmpty receiveState = iota
+	receiveValue
+	receiveClosed
+)
+
+func tryReceive[T any](input chan T) (T, receiveState) {
+	select {
+	case data, ok := <-input:
+		if !ok {
+			return data, receiveClosed
+		}
+
+		return data, receiveValue
+	default:
+		var zero T
+
+		return zero, receiveEmpty
+	}
+}
+
+func receiveAny[T any](ctx context.Context, inputs []chan T, open []bool) (int, T, bool, bool) {
+	var zero T
+
+	cases := make([]reflect.SelectCase, 0, len(inputs)+1)
+	indexes := make([]int, 0, len(inputs))
+
+	for index, input := range inputs {
+		if open[index] {
+			cases = append(cases, reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(input),
+				Send: reflect.Value{},
+			})
+			indexes = append(indexes, index)
+		}
+	}
+
+	if len(indexes) == 0 {
+		return -1, zero, false, false
+	}
+
+	cases = append(cases, reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+		Send: reflect.Value{},
+	})
+
+	chosen, value, ok := reflect.Select(cases)
+	if chosen == len(indexes) {
+		return -1, zero, false, true
+	}
+
+	if !ok {
+		return indexes[chosen], zero, false, false
+	}
+
+	data, _ := value.Interface().(T)
+
+	return indexes[chosen], data, true, false
+}
+
+func weightOf(weights []int, index int) int {
+	if index < len(weights) && weights[index] > 0 {
+		return weights[index]
+	}
+
+	return 1
+}
+
+func send[T any](ctx context.Context, output chan T, data T) bool {
+	select {
+	case output <- data:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func mergeAll(
+	multiplexer func(context.Context, []chan string, chan string) error,
+	inputs ...[]string,
+) []string {
+	channels := make([]chan string, len(inputs))
+	total := 0
+
+	for index, messages := range inputs {
+		channels[index] = make(chan string, len(messages))
+
+		for _, data := range messages {
+			channels[index] <- data
+		}
+
+		close(channels[index])
+
+		total += len(messages)
+	}
+
+	output := make(chan string, total)
+	_ = multiplexer(context.Background(), channels, output)
+
+	close(output)
+
+	result := make([]string, 0, total)
+	for data := range output {
+		result = append(result, data)
+	}
+
+	return result
+}
+
+func TestPriorityMultiplexer(t *testing.T) {
+	t.Parallel()
+
+	result := mergeAll(handlers.PriorityMultiplexer[string](), []string{"c1", "c2"}, []string{"d1", "d2"})
+
+	if !slices.Equal(result, []string{"c1", "c2", "d1", "d2"}) {
+		t.Fatalf("unexpected order: %v", result)
+	}
+}
+
+func TestWeightedMultiplexer(t *testing.T) {
+	t.Parallel()
+
+	result := mergeAll(handlers.WeightedMultiplexer[string](2, 1),
+		[]string{"a1", "a2", "a3", "a4"}, []string{"b1", "b2"})
+
+	if !slices.Equal(result, []string{"a1", "a2", "b1", "a3", "a4", "b2"}) {
+		t.Fatalf("unexpected order: %v", result)
+	}
+}
+
+func TestOrderedMergeMultiplexer(t *testing.T) {
+	t.Parallel()
+
+	less := func(a, b string) bool { return a < b }
+	result := mergeAll(handlers.OrderedMergeMultiplexer(less), []string{"a", "d", "e"}, []string{"b", "c", "f"})
+
+	if !slices.Equal(result, []string{"a", "b", "c", "d", "e", "f"}) {
+		t.Fatalf("unexpected order: %v", result)
+	}
+}
+
+func TestMultiplexerFuncFilters(t *testing.T) {
+	t.Parallel()
+
+	result := mergeAll(handlers.MultiplexerFunc, []string{"keep", "no multiplexer"})
+
+	if !slices.Equal(result, []string{"keep"}) {
+		t.Fatalf("unexpected result: %v", result)
+	}
+}
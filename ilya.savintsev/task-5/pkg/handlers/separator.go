@@ -2,15 +2,122 @@ package handlers
 
 import (
 	"context"
+	"hash/fnv"
+	"regexp"
 )
 
 func SeparatorFunc(ctx context.Context, input chan string, outputs []chan string) error {
+	return RoundRobinSeparator[string]()(ctx, input, outputs)
+}
+
+func RoundRobinSeparator[T any]() func(context.Context, chan T, []chan T) error {
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		var index int
+
+		return separate(ctx, input, outputs, func(T) int {
+			current := index
+			index = (index + 1) % len(outputs)
+
+			return current
+		})
+	}
+}
+
+func KeyHashSeparator[T any](key func(T) string) func(context.Context, chan T, []chan T) error {
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		return separate(ctx, input, outputs, func(data T) int {
+			hash := fnv.New32a()
+			_, _ = hash.Write([]byte(key(data)))
+
+			return int(hash.Sum32() % uint32(len(outputs)))
+		})
+	}
+}
+
+func PredicateSeparator[T any](predicates ...func(T) bool) func(context.Context, chan T, []chan T) error {
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		return separate(ctx, input, outputs, func(data T) int {
+			for index, predicate := range predicates {
+				if index < len(outputs) && predicate(data) {
+					return index
+				}
+			}
+
+			if len(predicates) < len(outputs) {
+				return len(predicates)
+			}
+
+			return -1
+		})
+	}
+}
+
+func RegexSeparator(patterns ...*regexp.Regexp) func(context.Context, chan string, []chan string) error {
+	predicates := make([]func(string) bool, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		predicates = append(predicates, pattern.MatchString)
+	}
+
+	return PredicateSeparator(predicates...)
+}
+
+func LeastLoadedSeparator[T any](depth func(output int) int) func(context.Context, chan T, []chan T) error {
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		depth := depth
+		if depth == nil {
+			depth = func(index int) int {
+				return len(outputs[index])
+			}
+		}
+
+		var start int
+
+		return separate(ctx, input, outputs, func(T) int {
+			best := start
+
+			for offset := range outputs {
+				index := (start + offset) % len(outputs)
+				if depth(index) < depth(best) {
+					best = index
+				}
+			}
+
+			start = (start + 1) % len(outputs)
+
+			return best
+		})
+	}
+}
+
+func BroadcastSeparator[T any]() func(context.Context, chan T, []chan T) error {
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		for {
+			select {
+			case data, ok := <-input:
+				if !ok {
+					return nil
+				}
+
+				for _, output := range outputs {
+					select {
+					case output <- data:
+					case <-ctx.Done():
+						return nil
+					}
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func separate[T any](ctx context.Context, input chan T, outputs []chan T, route func(T) int) error {
 	if len(outputs) == 0 {
 		return nil
 	}
 
-	var index int
-
 	for {
 		select {
 		case data, ok := <-input:
@@ -18,13 +125,16 @@ func SeparatorFunc(ctx context.Context, input chan string, outputs []chan string
 				return nil
 			}
 
+			index := route(data)
+			if index < 0 {
+				continue
+			}
+
 			select {
 			case outputs[index] <- data:
 			case <-ctx.Done():
 				return nil
 			}
-
-			index = (index + 1) % len(outputs)
 		case <-ctx.Done():
 			return nil
 		}
@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+func separateAll(
+	separator func(context.Context, chan string, []chan string) error,
+	outputsCount int,
+	messages ...string,
+) [][]string {
+	input := make(chan string, len(messages))
+	outputs := make([]chan string, outputsCount)
+
+	for index := range outputs {
+		outputs[index] = make(chan string, len(messages)*outputsCount)
+	}
+
+	for _, data := range messages {
+		input <- data
+	}
+
+	close(input)
+
+	_ = separator(context.Background(), input, outputs)
+
+	result := make([][]string, outputsCount)
+
+	for index, output := range outputs {
+		close(output)
+
+		for data := range output {
+			result[index] = append(result[index], data)
+		}
+	}
+
+	return result
+}
+
+func TestKeyHashSeparatorIsSticky(t *testing.T) {
+	t.Parallel()
+
+	key := func(data string) string { return data[:1] }
+	result := separateAll(handlers.KeyHashSeparator(key), 3, "a1", "b1", "a2", "c1", "a3")
+
+	for _, output := range result {
+		for _, data := range output {
+			if data[0] == 'a' && len(output) < 3 {
+				t.Fatalf("messages with key a were split: %v", result)
+			}
+		}
+	}
+}
+
+func TestRegexSeparatorFallback(t *testing.T) {
+	t.Parallel()
+
+	separator := handlers.RegexSeparator(regexp.MustCompile("^err"), regexp.MustCompile("^warn"))
+	result := separateAll(separator, 3, "err: x", "info: y", "warn: z")
+
+	if len(result[0]) != 1 || len(result[1]) != 1 || len(result[2]) != 1 || result[2][0] != "info: y" {
+		t.Fatalf("unexpected routing: %v", result)
+	}
+}
+
+func TestBroadcastSeparator(t *testing.T) {
+	t.Parallel()
+
+	result := separateAll(handlers.BroadcastSeparator[string](), 2, "x", "y")
+
+	if len(result[0]) != 2 || len(result[1]) != 2 {
+		t.Fatalf("unexpected broadcast: %v", result)
+	}
+}
+
+func TestLeastLoadedSeparator(t *testing.T) {
+	t.Parallel()
+
+	input := make(chan string, 1)
+	outputs := []chan string{make(chan string, 2), make(chan string, 2)}
+	outputs[0] <- "busy"
+
+	input <- "next"
+	close(input)
+
+	_ = handlers.LeastLoadedSeparator[string](nil)(context.Background(), input, outputs)
+
+	if len(outputs[1]) != 1 {
+		t.Fatalf("expected message on the least loaded output")
+	}
+}
+
+func TestLeastLoadedSeparatorUsesGivenDepth(t *testing.T) {
+	t.Parallel()
+
+	depths := []int{3, 0}
+	depth := func(output int) int {
+		return depths[output]
+	}
+
+	input := make(chan string, 3)
+	outputs := []chan string{make(chan string, 3), make(chan string, 3)}
+
+	for _, data := range []string{"x", "y", "z"} {
+		input <- data
+	}
+
+	close(input)
+
+	_ = handlers.LeastLoadedSeparator[string](depth)(context.Background(), input, outputs)
+
+	if len(outputs[0]) != 0 || len(outputs[1]) != 3 {
+		t.Fatalf("expected every message on the reported least loaded output")
+	}
+}
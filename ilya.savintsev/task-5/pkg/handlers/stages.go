@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/clock"
+)
+
+var (
+	ErrBatchSize  = errors.New("batch size must be positive")
+	ErrWindowSpan = errors.New("window size and slide must be positive")
+)
+
+type stamped[T any] struct {
+	data T
+	at   time.Time
+}
+
+func FilterDecorator[T any](keep func(T) bool) func(context.Context, chan T, chan T) error {
+	return func(ctx context.Context, input chan T, output chan T) error {
+		for {
+			select {
+			case data, ok := <-input:
+				if !ok {
+					return nil
+				}
+
+				if keep(data) && !send(ctx, output, data) {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func MapDecorator[T any](transform func(T) (T, error)) func(context.Context, chan T, chan T) error {
+	return func(ctx context.Context, input chan T, output chan T) error {
+		for {
+			select {
+			case data, ok := <-input:
+				if !ok {
+					return nil
+				}
+
+				mapped, err := transform(data)
+				if err != nil {
+					return err
+				}
+
+				if !send(ctx, output, mapped) {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func BatchDecorator[T, U any](
+	size int,
+	interval time.Duration,
+	merge func([]T) U,
+) func(context.Context, chan T, chan U) error {
+	return BatchDecoratorWithClock(clock.Real(), size, interval, merge)
+}
+
+func BatchDecoratorWithClock[T, U any](
+	clk clock.Clock,
+	size int,
+	interval time.Duration,
+	merge func([]T) U,
+) func(context.Context, chan T, chan U) error {
+	return func(ctx context.Context, input chan T, output chan U) error {
+		if size < 1 {
+			return fmt.Errorf("%w: %d", ErrBatchSize, size)
+		}
+
+		batch := make([]T, 0, size)
+		timer := clk.NewTimer(interval)
+		clock.StopTimer(timer)
+
+		defer timer.Stop()
+
+		flush := func() bool {
+			clock.StopTimer(timer)
+
+			if len(batch) == 0 {
+				return true
+			}
+
+			merged := merge(batch)
+			batch = make([]T, 0, size)
+
+			return send(ctx, output, merged)
+		}
+
+		for {
+			select {
+			case data, ok := <-input:
+				if !ok {
+					flush()
+
+					return nil
+				}
+
+				if len(batch) == 0 {
+					timer.Reset(interval)
+				}
+
+				batch = append(batch, data)
+
+				if len(batch) >= size && !flush() {
+					return nil
+				}
+			case <-timer.C():
+				if !flush() {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func WindowDecorator[T, U any](
+	size, slide time.Duration,
+	aggregate func([]T) U,
+) func(context.Context, chan T, chan U) error {
+	return WindowDecoratorWithClock(clock.Real(), size, slide, aggregate)
+}
+
+func WindowDecoratorWithClock[T, U any](
+	clk clock.Clock,
+	size, slide time.Duration,
+	aggregate func([]T) U,
+) func(context.Context, chan T, chan U) error {
+	return func(ctx context.Context, input chan T, output chan U) error {
+		if size <= 0 || slide <= 0 {
+			return fmt.Errorf("%w: size %s, slide %s", ErrWindowSpan, size, slide)
+		}
+
+		window := make([]stamped[T], 0)
+		ticker := clk.NewTicker(slide)
+		fresh := false
+
+		defer ticker.Stop()
+
+		emit := func(now time.Time) bool {
+			start := 0
+			for start < len(window) && now.Sub(window[start].at) >= size {
+				start++
+			}
+
+			window = window[start:]
+			if len(window) == 0 {
+				return true
+			}
+
+			fresh = false
+
+			items := make([]T, 0, len(window))
+			for _, item := range window {
+				items = append(items, item.data)
+			}
+
+			return send(ctx, output, aggregate(items))
+		}
+
+		for {
+			select {
+			case data, ok := <-input:
+				if !ok {
+					if fresh {
+						emit(clk.Now())
+					}
+
+					return nil
+				}
+
+				window = append(window, stamped[T]{data: data, at: clk.Now()})
+				fresh = true
+			case now := <-ticker.C():
+				if !emit(now) {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyertest"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+type stampClock struct {
+	*conveyertest.FakeClock
+
+	stamps chan time.Time
+}
+
+func (c stampClock) Now() time.Time {
+	now := c.FakeClock.Now()
+	c.stamps <- now
+
+	return now
+}
+
+func joinAll(items []string) string {
+	return strings.Join(items, ",")
+}
+
+func TestBatchDecoratorFlushesBySize(t *testing.T) {
+	t.Parallel()
+
+	input := make(chan string, 5)
+	output := make(chan string, 5)
+
+	for _, data := range []string{"a", "b", "c", "d", "e"} {
+		input <- data
+	}
+
+	close(input)
+
+	_ = handlers.BatchDecorator(2, time.Hour, joinAll)(context.Background(), input, output)
+	close(output)
+
+	got := make([]string, 0, 3)
+	for data := range output {
+		got = append(got, data)
+	}
+
+	if joinAll(got) != "a,b,c,d,e" || len(got) != 3 {
+		t.Fatalf("unexpected batches: %q", got)
+	}
+}
+
+func TestBatchDecoratorFlushesByInterval(t *testing.T) {
+	t.Parallel()
+
+	input := make(chan string)
+	output := make(chan string, 1)
+
+	go func() {
+		_ = handlers.BatchDecorator(10, 10*time.Millisecond, joinAll)(context.Background(), input, output)
+	}()
+
+	input <- "a"
+
+	select {
+	case data := <-output:
+		if data != "a" {
+			t.Fatalf("unexpected batch: %q", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("batch was not flushed by interval")
+	}
+
+	close(input)
+}
+
+func TestFilterAndMapDecorators(t *testing.T) {
+	t.Parallel()
+
+	input := make(chan string, 3)
+	middle := make(chan string, 3)
+	output := make(chan string, 3)
+
+	for _, data := range []string{"keep", "drop", "keep"} {
+		input <- data
+	}
+
+	close(input)
+
+	_ = handlers.FilterDecorator(func(data string) bool { return data == "keep" })(context.Background(), input, middle)
+	close(middle)
+
+	_ = handlers.MapDecorator(func(data string) (string, error) {
+		return strings.ToUpper(data), nil
+	})(context.Background(), middle, output)
+	close(output)
+
+	got := make([]string, 0, 2)
+	for data := range output {
+		got = append(got, data)
+	}
+
+	if joinAll(got) != "KEEP,KEEP" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+}
+
+func TestBatchDecoratorMergesIntoAnotherType(t *testing.T) {
+	t.Parallel()
+
+	input := make(chan string, 3)
+	output := make(chan int, 2)
+
+	for _, data := range []string{"a", "b", "c"} {
+		input <- data
+	}
+
+	close(input)
+
+	count := func(items []string) int { return len(items) }
+	if err := handlers.BatchDecorator(2, time.Hour, count)(context.Background(), input, output); err != nil {
+		t.Fatalf("unexpected batch error: %v", err)
+	}
+
+	if first, second := <-output, <-output; first != 2 || second != 1 {
+		t.Fatalf("unexpected batch sizes: %d, %d", first, second)
+	}
+}
+
+func TestBatchAndWindowRejectBadSizes(t *testing.T) {
+	t.Parallel()
+
+	input := make(chan string)
+	output := make(chan string)
+
+	batch := handlers.BatchDecorator(-1, time.Second, joinAll)
+	if err := batch(context.Background(), input, output); !errors.Is(err, handlers.ErrBatchSize) {
+		t.Fatalf("expected ErrBatchSize, got %v", err)
+	}
+
+	window := handlers.WindowDecorator(time.Second, 0, joinAll)
+	if err := window(context.Background(), input, output); !errors.Is(err, handlers.ErrWindowSpan) {
+		t.Fatalf("expected ErrWindowSpan, got %v", err)
+	}
+}
+
+func TestWindowDecoratorSlidesAndExpires(t *testing.T) {
+	t.Parallel()
+
+	clk := stampClock{FakeClock: conveyertest.NewFakeClock(time.Unix(0, 0)), stamps: make(chan time.Time, 4)}
+	input := make(chan string)
+	output := make(chan string, 4)
+	finished := make(chan error, 1)
+
+	go func() {
+		finished <- handlers.WindowDecoratorWithClock(clk, 2*time.Second, time.Second, joinAll)(
+			context.Background(), input, output)
+	}()
+
+	clk.BlockUntil(1)
+
+	push := func(data string) {
+		input <- data
+		<-clk.stamps
+	}
+
+	expect := func(want string) {
+		t.Helper()
+
+		select {
+		case got := <-output:
+			if got != want {
+				t.Fatalf("unexpected window: got %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("window %q was not emitted", want)
+		}
+	}
+
+	push("a")
+	clk.Advance(time.Second)
+	expect("a")
+
+	push("b")
+	clk.Advance(time.Second)
+	expect("b")
+
+	push("c")
+	close(input)
+	<-clk.stamps
+	expect("b,c")
+
+	if err := <-finished; err != nil {
+		t.Fatalf("unexpected window error: %v", err)
+	}
+}
+
+func TestWindowDecoratorDoesNotRepeatReportedWindowOnClose(t *testing.T) {
+	t.Parallel()
+
+	clk := stampClock{FakeClock: conveyertest.NewFakeClock(time.Unix(0, 0)), stamps: make(chan time.Time, 4)}
+	input := make(chan string)
+	output := make(chan string, 4)
+	finished := make(chan error, 1)
+
+	go func() {
+		finished <- handlers.WindowDecoratorWithClock(clk, 2*time.Second, time.Second, joinAll)(
+			context.Background(), input, output)
+	}()
+
+	clk.BlockUntil(1)
+
+	input <- "a"
+	<-clk.stamps
+	clk.Advance(time.Second)
+
+	if got := <-output; got != "a" {
+		t.Fatalf("unexpected window: %q", got)
+	}
+
+	close(input)
+
+	if err := <-finished; err != nil {
+		t.Fatalf("unexpected window error: %v", err)
+	}
+
+	if len(output) != 0 {
+		t.Fatalf("reported window was emitted again on close: %q", <-output)
+	}
+}
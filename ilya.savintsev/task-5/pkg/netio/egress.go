@@ -0,0 +1,103 @@
+package netio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+)
+
+const eventStream = "text/event-stream"
+
+type Receiver interface {
+	RecvContext(ctx context.Context, output string) (string, error)
+}
+
+// HTTPEgress delivers at most once: a message taken from output is lost if writing it to the client fails.
+func HTTPEgress(receiver Receiver, output string) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet {
+			writer.Header().Set("Allow", http.MethodGet)
+			http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		flusher, ok := writer.(http.Flusher)
+		if !ok {
+			http.Error(writer, "streaming unsupported", http.StatusInternalServerError)
+
+			return
+		}
+
+		sse := strings.Contains(request.Header.Get("Accept"), eventStream)
+		if sse {
+			writer.Header().Set("Content-Type", eventStream)
+			writer.Header().Set("Cache-Control", "no-cache")
+		} else {
+			writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+
+		writer.Header().Set("X-Content-Type-Options", "nosniff")
+		writer.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		ctx := request.Context()
+
+		for {
+			data, err := receiver.RecvContext(ctx, output)
+			if err != nil {
+				if sse && ctx.Err() == nil {
+					_ = writeEvent(writer, "error", errorText(err))
+					flusher.Flush()
+				}
+
+				return
+			}
+
+			if sse {
+				err = writeEvent(writer, "", data)
+			} else {
+				_, err = fmt.Fprintln(writer, data)
+			}
+
+			if err != nil {
+				return
+			}
+
+			flusher.Flush()
+		}
+	})
+}
+
+func writeEvent(writer io.Writer, event, data string) error {
+	var builder strings.Builder
+
+	if event != "" {
+		fmt.Fprintf(&builder, "event: %s\n", event)
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&builder, "data: %s\n", line)
+	}
+
+	builder.WriteString("\n")
+
+	if _, err := io.WriteString(writer, builder.String()); err != nil {
+		return fmt.Errorf("write event: %w", err)
+	}
+
+	return nil
+}
+
+func errorText(err error) string {
+	if errors.Is(err, conveyer.ErrChannelClosed) {
+		return "closed"
+	}
+
+	return err.Error()
+}
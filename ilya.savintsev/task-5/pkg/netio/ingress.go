@@ -0,0 +1,151 @@
+package netio
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+)
+
+const maxBodySize = 1 << 20
+
+var ErrListenerClosed = errors.New("ingress listener closed")
+
+type Sender interface {
+	SendContext(ctx context.Context, input string, data string) error
+}
+
+func HTTPIngress(sender Sender, input string) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			writer.Header().Set("Allow", http.MethodPost)
+			http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxBodySize))
+		if err != nil {
+			http.Error(writer, err.Error(), bodyStatus(err))
+
+			return
+		}
+
+		data := strings.TrimSuffix(strings.TrimSuffix(string(body), "\n"), "\r")
+
+		if err := sender.SendContext(request.Context(), input, data); err != nil {
+			http.Error(writer, err.Error(), statusOf(err))
+
+			return
+		}
+
+		writer.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func ServeTCP(ctx context.Context, listener net.Listener, sender Sender, input string) error {
+	var (
+		conns   sync.WaitGroup
+		mu      sync.Mutex
+		open    = make(map[net.Conn]struct{})
+		stopped bool
+	)
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = listener.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		stopped = true
+
+		for conn := range open {
+			_ = conn.Close()
+		}
+	})
+	defer stop()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			conns.Wait()
+
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("%w: %w", ErrListenerClosed, err)
+		}
+
+		mu.Lock()
+		if stopped {
+			mu.Unlock()
+
+			_ = conn.Close()
+
+			continue
+		}
+
+		open[conn] = struct{}{}
+		conns.Add(1)
+		mu.Unlock()
+
+		go func() {
+			defer conns.Done()
+
+			serveLines(ctx, conn, sender, input)
+
+			mu.Lock()
+			delete(open, conn)
+			mu.Unlock()
+
+			_ = conn.Close()
+		}()
+	}
+}
+
+func serveLines(ctx context.Context, conn net.Conn, sender Sender, input string) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxBodySize)
+
+	for scanner.Scan() {
+		data := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if err := sender.SendContext(ctx, input, data); err != nil {
+			fmt.Fprintf(conn, "error: %v\n", err)
+
+			return
+		}
+	}
+}
+
+func bodyStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, conveyer.ErrChanNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, conveyer.ErrChannelClosed):
+		return http.StatusGone
+	case errors.Is(err, conveyer.ErrFullChannel), errors.Is(err, conveyer.ErrTimeout):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
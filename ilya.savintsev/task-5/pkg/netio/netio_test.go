@@ -0,0 +1,343 @@
+package netio_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+	"github.com/faxryzen/task-5/pkg/netio"
+)
+
+func startConveyer(t *testing.T) *conveyer.DefaultConveyer {
+	t.Helper()
+
+	conv := conveyer.New(4)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		_ = conv.Run(ctx)
+	}()
+
+	return conv
+}
+
+func recv(t *testing.T, conv *conveyer.DefaultConveyer) string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	data, err := conv.RecvContext(ctx, "out")
+	if err != nil {
+		t.Fatalf("unexpected recv error: %v", err)
+	}
+
+	return data
+}
+
+func TestHTTPIngress(t *testing.T) {
+	t.Parallel()
+
+	conv := startConveyer(t)
+	server := httptest.NewServer(netio.HTTPIngress(conv, "in"))
+	t.Cleanup(server.Close)
+
+	response, err := http.Post(server.URL, "text/plain", strings.NewReader("hello\n"))
+	if err != nil {
+		t.Fatalf("unexpected post error: %v", err)
+	}
+
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d", response.StatusCode)
+	}
+
+	if got := recv(t, conv); got != "decorated: hello" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+
+	response, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status for GET: %d", response.StatusCode)
+	}
+}
+
+func TestHTTPIngressUnknownChannel(t *testing.T) {
+	t.Parallel()
+
+	conv := startConveyer(t)
+	server := httptest.NewServer(netio.HTTPIngress(conv, "missing"))
+	t.Cleanup(server.Close)
+
+	response, err := http.Post(server.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("unexpected post error: %v", err)
+	}
+
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d", response.StatusCode)
+	}
+}
+
+func TestServeTCP(t *testing.T) {
+	t.Parallel()
+
+	conv := startConveyer(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected listen error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+
+	go func() {
+		served <- netio.ServeTCP(ctx, listener, conv, "in")
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected dial error: %v", err)
+	}
+
+	fmt.Fprint(conn, "a\r\nb\n")
+
+	for _, want := range []string{"decorated: a", "decorated: b"} {
+		if got := recv(t, conv); got != want {
+			t.Fatalf("unexpected output: got %q, want %q", got, want)
+		}
+	}
+
+	cancel()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("unexpected serve error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ServeTCP did not stop")
+	}
+
+	conn.Close()
+}
+
+func TestHTTPEgressChunked(t *testing.T) {
+	t.Parallel()
+
+	conv := startConveyer(t)
+	server := httptest.NewServer(netio.HTTPEgress(conv, "out"))
+	t.Cleanup(server.Close)
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	defer response.Body.Close()
+
+	for _, data := range []string{"a", "b"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	reader := bufio.NewReader(response.Body)
+
+	for _, want := range []string{"decorated: a\n", "decorated: b\n"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected read error: %v", err)
+		}
+
+		if line != want {
+			t.Fatalf("unexpected line: got %q, want %q", line, want)
+		}
+	}
+}
+
+func TestHTTPEgressSSE(t *testing.T) {
+	t.Parallel()
+
+	conv := startConveyer(t)
+	server := httptest.NewServer(netio.HTTPEgress(conv, "out"))
+	t.Cleanup(server.Close)
+
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+
+	request.Header.Set("Accept", "text/event-stream")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	defer response.Body.Close()
+
+	if got := response.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("unexpected content type: %q", got)
+	}
+
+	if err := conv.Send("in", "line1\nline2"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	reader := bufio.NewReader(response.Body)
+
+	for _, want := range []string{"data: decorated: line1\n", "data: line2\n", "\n"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected read error: %v", err)
+		}
+
+		if line != want {
+			t.Fatalf("unexpected line: got %q, want %q", line, want)
+		}
+	}
+}
+
+var errBroken = errors.New("broken body")
+
+type brokenBody struct{}
+
+func (brokenBody) Read([]byte) (int, error) {
+	return 0, errBroken
+}
+
+func TestHTTPIngressBodyErrors(t *testing.T) {
+	t.Parallel()
+
+	conv := startConveyer(t)
+	handler := netio.HTTPIngress(conv, "in")
+
+	cases := map[string]struct {
+		body io.Reader
+		want int
+	}{
+		"too large": {body: strings.NewReader(strings.Repeat("x", 2<<20)), want: http.StatusRequestEntityTooLarge},
+		"broken":    {body: brokenBody{}, want: http.StatusBadRequest},
+	}
+
+	for name, current := range cases {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", current.body))
+
+		if recorder.Code != current.want {
+			t.Fatalf("%s: unexpected status: got %d, want %d", name, recorder.Code, current.want)
+		}
+	}
+}
+
+type lateListener struct {
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *lateListener) Accept() (net.Conn, error) {
+	if conn, ok := <-l.conns; ok {
+		return conn, nil
+	}
+
+	return nil, net.ErrClosed
+}
+
+func (l *lateListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+
+	return nil
+}
+
+func (l *lateListener) Addr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0, Zone: ""}
+}
+
+func TestServeTCPClosesConnAcceptedDuringShutdown(t *testing.T) {
+	t.Parallel()
+
+	conv := startConveyer(t)
+	listener := &lateListener{conns: make(chan net.Conn), closed: make(chan struct{}), closeOnce: sync.Once{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+
+	go func() {
+		served <- netio.ServeTCP(ctx, listener, conv, "in")
+	}()
+
+	cancel()
+	<-listener.closed
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	listener.conns <- server
+	close(listener.conns)
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("unexpected serve error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ServeTCP hung on a connection accepted during shutdown")
+	}
+
+	if _, err := client.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("late connection was left open: %v", err)
+	}
+}
+
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (brokenWriter) Write([]byte) (int, error) {
+	return 0, errBroken
+}
+
+func TestHTTPEgressIsAtMostOnce(t *testing.T) {
+	t.Parallel()
+
+	conv := startConveyer(t)
+
+	for _, data := range []string{"a", "b"} {
+		if err := conv.Send("in", data); err != nil {
+			t.Fatalf("unexpected send error: %v", err)
+		}
+	}
+
+	writer := brokenWriter{ResponseRecorder: httptest.NewRecorder()}
+	netio.HTTPEgress(conv, "out").ServeHTTP(writer, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := recv(t, conv); got != "decorated: b" {
+		t.Fatalf("failed write should drop only the message it took, next is %q", got)
+	}
+}
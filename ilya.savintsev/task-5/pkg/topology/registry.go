@@ -0,0 +1,183 @@
+package topology
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/handlers"
+)
+
+type (
+	DecoratorFactory   func(stage Stage) (conveyer.DecoratorFunc[string], error)
+	MultiplexerFactory func(stage Stage) (conveyer.MultiplexerFunc[string], error)
+	SeparatorFactory   func(stage Stage, depth func(output int) int) (conveyer.SeparatorFunc[string], error)
+)
+
+type Registry struct {
+	decorators   map[string]DecoratorFactory
+	multiplexers map[string]MultiplexerFactory
+	separators   map[string]SeparatorFactory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		decorators:   make(map[string]DecoratorFactory),
+		multiplexers: make(map[string]MultiplexerFactory),
+		separators:   make(map[string]SeparatorFactory),
+	}
+}
+
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+
+	registry.AddDecorator("prefix-decorator", func(Stage) (conveyer.DecoratorFunc[string], error) {
+		return handlers.PrefixDecoratorFunc, nil
+	})
+	registry.AddMultiplexer("filtering-multiplexer", func(Stage) (conveyer.MultiplexerFunc[string], error) {
+		return handlers.MultiplexerFunc, nil
+	})
+	registry.AddMultiplexer("merge-multiplexer", func(Stage) (conveyer.MultiplexerFunc[string], error) {
+		return handlers.MergeMultiplexer[string](), nil
+	})
+	registry.AddMultiplexer("priority-multiplexer", func(Stage) (conveyer.MultiplexerFunc[string], error) {
+		return handlers.PriorityMultiplexer[string](), nil
+	})
+	registry.AddMultiplexer("weighted-multiplexer", weightedMultiplexer)
+	registry.AddMultiplexer("ordered-merge-multiplexer", func(Stage) (conveyer.MultiplexerFunc[string], error) {
+		return handlers.OrderedMergeMultiplexer(func(a, b string) bool { return a < b }), nil
+	})
+	registry.AddSeparator("round-robin-separator", func(Stage, func(int) int) (conveyer.SeparatorFunc[string], error) {
+		return handlers.SeparatorFunc, nil
+	})
+	registry.AddSeparator("key-hash-separator", keyHashSeparator)
+	registry.AddSeparator("regex-separator", regexSeparator)
+	registry.AddSeparator("least-loaded-separator", leastLoadedSeparator)
+	registry.AddSeparator("broadcast-separator", func(Stage, func(int) int) (conveyer.SeparatorFunc[string], error) {
+		return handlers.BroadcastSeparator[string](), nil
+	})
+
+	return registry
+}
+
+func weightedMultiplexer(stage Stage) (conveyer.MultiplexerFunc[string], error) {
+	weights := make([]int, 0, len(stage.Inputs))
+
+	for _, input := range stage.Inputs {
+		weight := 1
+
+		if value, ok := stage.Params[input]; ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("%w: weight of %q must be a positive integer", ErrStageParams, input)
+			}
+
+			weight = parsed
+		}
+
+		weights = append(weights, weight)
+	}
+
+	return handlers.WeightedMultiplexer[string](weights...), nil
+}
+
+func keyHashSeparator(stage Stage, _ func(int) int) (conveyer.SeparatorFunc[string], error) {
+	delimiter := stage.Params["delimiter"]
+
+	return handlers.KeyHashSeparator(func(data string) string {
+		if delimiter == "" {
+			return data
+		}
+
+		key, _, _ := strings.Cut(data, delimiter)
+
+		return key
+	}), nil
+}
+
+func regexSeparator(stage Stage, _ func(int) int) (conveyer.SeparatorFunc[string], error) {
+	patterns := make([]*regexp.Regexp, 0, len(stage.Outputs))
+
+	for _, output := range stage.Outputs {
+		expr, ok := stage.Params[output]
+		if !ok {
+			break
+		}
+
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: output %q: %w", ErrStageParams, output, err)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return handlers.RegexSeparator(patterns...), nil
+}
+
+func leastLoadedSeparator(_ Stage, depth func(int) int) (conveyer.SeparatorFunc[string], error) {
+	return handlers.LeastLoadedSeparator[string](depth), nil
+}
+
+func (r *Registry) AddDecorator(name string, factory DecoratorFactory) {
+	r.decorators[name] = factory
+}
+
+func (r *Registry) AddMultiplexer(name string, factory MultiplexerFactory) {
+	r.multiplexers[name] = factory
+}
+
+func (r *Registry) AddSeparator(name string, factory SeparatorFactory) {
+	r.separators[name] = factory
+}
+
+func (r *Registry) register(conv *conveyer.DefaultConveyer, stage Stage) error {
+	if factory, ok := r.decorators[stage.Handler]; ok {
+		if len(stage.Inputs) != 1 || len(stage.Outputs) != 1 {
+			return fmt.Errorf("%w: %q needs one input and one output", ErrStageShape, stage.Handler)
+		}
+
+		handlerFunc, err := factory(stage)
+		if err != nil {
+			return fmt.Errorf("building %q: %w", stage.Handler, err)
+		}
+
+		conv.RegisterDecorator(handlerFunc, stage.Inputs[0], stage.Outputs[0])
+
+		return nil
+	}
+
+	if factory, ok := r.multiplexers[stage.Handler]; ok {
+		if len(stage.Inputs) == 0 || len(stage.Outputs) != 1 {
+			return fmt.Errorf("%w: %q needs inputs and one output", ErrStageShape, stage.Handler)
+		}
+
+		handlerFunc, err := factory(stage)
+		if err != nil {
+			return fmt.Errorf("building %q: %w", stage.Handler, err)
+		}
+
+		conv.RegisterMultiplexer(handlerFunc, stage.Inputs, stage.Outputs[0])
+
+		return nil
+	}
+
+	if factory, ok := r.separators[stage.Handler]; ok {
+		if len(stage.Inputs) != 1 || len(stage.Outputs) == 0 {
+			return fmt.Errorf("%w: %q needs one input and outputs", ErrStageShape, stage.Handler)
+		}
+
+		handlerFunc, err := factory(stage, conv.Depths(stage.Outputs))
+		if err != nil {
+			return fmt.Errorf("building %q: %w", stage.Handler, err)
+		}
+
+		conv.RegisterSeparator(handlerFunc, stage.Inputs[0], stage.Outputs)
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrUnknownHandler, stage.Handler)
+}
@@ -0,0 +1,134 @@
+package topology
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faxryzen/task-5/pkg/conveyer"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	ErrUnknownFormat  = errors.New("unknown topology format")
+	ErrUnknownHandler = errors.New("unknown handler")
+	ErrStageShape     = errors.New("wrong number of stage channels")
+	ErrStageParams    = errors.New("invalid stage params")
+	ErrReadTopology   = errors.New("unable read topology")
+	ErrDecodeTopology = errors.New("invalid topology")
+)
+
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+type Channel struct {
+	Name   string `json:"name"   yaml:"name"`
+	Buffer int    `json:"buffer" yaml:"buffer"`
+}
+
+type Stage struct {
+	Handler string            `json:"handler" yaml:"handler"`
+	Inputs  []string          `json:"inputs"  yaml:"inputs"`
+	Outputs []string          `json:"outputs" yaml:"outputs"`
+	Params  map[string]string `json:"params"  yaml:"params"`
+}
+
+type Topology struct {
+	BufferSize int       `json:"buffer-size" yaml:"buffer-size"`
+	Inputs     []string  `json:"inputs"      yaml:"inputs"`
+	Outputs    []string  `json:"outputs"     yaml:"outputs"`
+	Channels   []Channel `json:"channels"    yaml:"channels"`
+	Stages     []Stage   `json:"stages"      yaml:"stages"`
+}
+
+func Parse(data []byte, format Format) (*Topology, error) {
+	var (
+		topology Topology
+		err      error
+	)
+
+	switch format {
+	case FormatYAML:
+		err = yaml.UnmarshalStrict(data, &topology)
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		err = decoder.Decode(&topology)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodeTopology, err)
+	}
+
+	return &topology, nil
+}
+
+func ReadFile(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadTopology, err)
+	}
+
+	format, err := formatByExt(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data, format)
+}
+
+func LoadFile(path string, registry *Registry) (*conveyer.DefaultConveyer, error) {
+	topology, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return topology.Build(registry)
+}
+
+func (t *Topology) Build(registry *Registry) (*conveyer.DefaultConveyer, error) {
+	conv := conveyer.New(t.BufferSize)
+
+	for _, channel := range t.Channels {
+		if err := conv.RegisterChannel(channel.Name, channel.Buffer); err != nil {
+			return nil, fmt.Errorf("channel %q: %w", channel.Name, err)
+		}
+	}
+
+	for _, stage := range t.Stages {
+		if err := registry.register(conv, stage); err != nil {
+			return nil, err
+		}
+	}
+
+	conv.DeclareInputs(t.Inputs...)
+	conv.DeclareOutputs(t.Outputs...)
+
+	if err := conv.Validate(); err != nil {
+		return nil, fmt.Errorf("topology check: %w", err)
+	}
+
+	return conv, nil
+}
+
+func formatByExt(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, path)
+	}
+}
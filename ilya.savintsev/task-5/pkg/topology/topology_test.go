@@ -0,0 +1,76 @@
+package topology_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/faxryzen/task-5/pkg/topology"
+)
+
+func TestLoadFileYAML(t *testing.T) {
+	t.Parallel()
+
+	conv, err := topology.LoadFile("testdata/pipeline.yaml", topology.DefaultRegistry())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = conv.Run(ctx)
+	}()
+
+	if err := conv.Send("input", "msg"); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	data, err := conv.Recv("output")
+	if err != nil {
+		t.Fatalf("unexpected recv error: %v", err)
+	}
+
+	if data != "decorated: msg" {
+		t.Fatalf("unexpected data: %q", data)
+	}
+}
+
+func TestLoadFileJSON(t *testing.T) {
+	t.Parallel()
+
+	if _, err := topology.LoadFile("testdata/pipeline.json", topology.DefaultRegistry()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBuildUnknownHandler(t *testing.T) {
+	t.Parallel()
+
+	source := "stages:\n  - handler: nope\n    inputs: [a]\n    outputs: [b]\n"
+
+	spec, err := topology.Parse([]byte(source), topology.FormatYAML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := spec.Build(topology.DefaultRegistry()); !errors.Is(err, topology.ErrUnknownHandler) {
+		t.Fatalf("expected ErrUnknownHandler, got %v", err)
+	}
+}
+
+func TestParseRejectsUnknownKeys(t *testing.T) {
+	t.Parallel()
+
+	documents := map[topology.Format]string{
+		topology.FormatJSON: `{"buffer-size": 1, "stages": [{"handler": "prefix", "input": ["a"], "outputs": ["b"]}]}`,
+		topology.FormatYAML: "buffer-size: 1\nstages:\n  - handler: prefix\n    input: [a]\n    outputs: [b]\n",
+	}
+
+	for format, document := range documents {
+		if _, err := topology.Parse([]byte(document), format); !errors.Is(err, topology.ErrDecodeTopology) {
+			t.Fatalf("expected ErrDecodeTopology for %s, got %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package conveyer_test
+
+import (
+	"testing"
+
+	"github.com/Ksenia-rgb/task-5/pkg/conveyer"
+	"github.com/faxryzen/task-5/pkg/contract"
+	"github.com/faxryzen/task-5/pkg/contracttest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	contracttest.RunConformance(t, func(size int) contract.Conveyer {
+		conv := conveyer.New(size)
+
+		return &conv
+	})
+}
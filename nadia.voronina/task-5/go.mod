@@ -2,4 +2,9 @@ module spbstu.ru/nadia.voronina/task-5
 
 go 1.22.7
 
-require golang.org/x/sync v0.11.0
+require (
+	github.com/faxryzen/task-5 v0.0.0
+	golang.org/x/sync v0.11.0
+)
+
+replace github.com/faxryzen/task-5 => ../../ilya.savintsev/task-5
@@ -0,0 +1,19 @@
+package conveyer_test
+
+import (
+	"testing"
+
+	"github.com/faxryzen/task-5/pkg/contract"
+	"github.com/faxryzen/task-5/pkg/contracttest"
+	"spbstu.ru/nadia.voronina/task-5/pkg/conveyer"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	contracttest.RunConformance(t, func(size int) contract.Conveyer {
+		conv := conveyer.New(size)
+
+		return &conv
+	})
+}
@@ -0,0 +1,17 @@
+package conveyer_test
+
+import (
+	"testing"
+
+	"github.com/faxryzen/task-5/pkg/contract"
+	"github.com/faxryzen/task-5/pkg/contracttest"
+	"github.com/widgeiw/task-5/pkg/conveyer"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	contracttest.RunConformance(t, func(size int) contract.Conveyer {
+		return conveyer.New(size)
+	})
+}